@@ -24,6 +24,10 @@ Examples:
     xgo build -o main -gcflags="all=-N -l" ./    build current module with debug flags
     xgo run ./                                   run current module
     xgo test ./...                               test all test cases of current module
+    xgo test --detect-leaks ./...                report goroutines still running after each test
+    xgo test --global-deterministic-maps ./...   test with reproducible order of all maps
+    xgo test --report-var-mutation ./...         report tests mutating package variables
+    xgo test --skip-init=github.com/a/db ./...   skip init functions of matching packages
     xgo exec go version                          print instrumented go version
     xgo tool trace TestSomething.json            view test trace
 
@@ -147,10 +147,26 @@ func handleBuild(cmd string, args []string) error {
 	withGoroot := opts.withGoroot
 	dumpIR := opts.dumpIR
 	dumpAST := opts.dumpAST
+	detectLeaks := opts.detectLeaks
+	mapSeed := opts.mapSeed
+	reportVarMutation := opts.reportVarMutation
+	skipInits := opts.skipInits
 
 	if cmdExec && len(remainArgs) == 0 {
 		return fmt.Errorf("exec requires command")
 	}
+	if detectLeaks && !cmdTest {
+		return fmt.Errorf("--detect-leaks only applies to test")
+	}
+	if mapSeed != "" && !cmdTest {
+		return fmt.Errorf("--global-deterministic-maps only applies to test")
+	}
+	if reportVarMutation && !cmdTest {
+		return fmt.Errorf("--report-var-mutation only applies to test")
+	}
+	if len(skipInits) > 0 && !cmdTest {
+		return fmt.Errorf("--skip-init only applies to test")
+	}
 
 	closeDebug, err = setupDebugLog(logDebugOption)
 	if err != nil {
@@ -171,6 +187,14 @@ func handleBuild(cmd string, args []string) error {
 	}
 	logDebug("effective GOROOT: %s", goroot)
 
+	if cmdTest && !noInstrument {
+		stubInits, err := findStubInits(goroot, projectDir, remainArgs)
+		if err != nil {
+			return err
+		}
+		skipInits = append(skipInits, stubInits...)
+	}
+
 	// create a tmp dir for communication with exec_tool
 	tmpDir, err := os.MkdirTemp("", "xgo-"+cmd)
 	if err != nil {
@@ -240,6 +264,11 @@ func handleBuild(cmd string, args []string) error {
 	if gcflags != "" {
 		buildCacheSuffix = "-gcflags"
 	}
+	if len(skipInits) > 0 {
+		// the compiler reads patterns from env,
+		// which is not part of go's cache key
+		buildCacheSuffix += "-skip-init-" + skipInitsSum(skipInits)
+	}
 	buildCacheDir := filepath.Join(instrumentDir, "build-cache"+buildCacheSuffix)
 	revisionFile := filepath.Join(instrumentDir, "xgo-revision.txt")
 	fullSyncRecord := filepath.Join(instrumentDir, "full-sync-record.txt")
@@ -435,6 +464,21 @@ func handleBuild(cmd string, args []string) error {
 		if vscodeDebugFile != "" {
 			execCmd.Env = append(execCmd.Env, "XGO_DEBUG_VSCODE="+vscodeDebugFile+vscodeDebugFileSuffix)
 		}
+		if len(skipInits) > 0 {
+			execCmd.Env = append(execCmd.Env, "XGO_SKIP_INIT="+strings.Join(skipInits, ","))
+		}
+	}
+	if detectLeaks {
+		// read by runtime/leak in the test binary
+		execCmd.Env = append(execCmd.Env, "XGO_DETECT_LEAKS=true")
+	}
+	if mapSeed != "" {
+		// read by the patched runtime before any map is created
+		execCmd.Env = append(execCmd.Env, "XGO_DETERMINISTIC_MAPS="+mapSeed)
+	}
+	if reportVarMutation {
+		// read by runtime/mock in the test binary
+		execCmd.Env = append(execCmd.Env, "XGO_REPORT_VAR_MUTATION=true")
 	}
 	logDebug("command env: %v", execCmd.Env)
 	execCmd.Stdout = os.Stdout
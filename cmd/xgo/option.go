@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/xhd2015/xgo/support/flag"
@@ -30,6 +31,14 @@ type options struct {
 	resetInstrument bool
 	noSetup         bool
 
+	// test only
+	detectLeaks bool
+	// seed of --global-deterministic-maps, empty if not set
+	mapSeed           string
+	reportVarMutation bool
+	// package patterns of --skip-init
+	skipInits []string
+
 	// dev only
 	debugWithDlv bool
 	xgoHome      string
@@ -62,6 +71,11 @@ func parseOptions(args []string) (*options, error) {
 	var resetInstrument bool
 	var noSetup bool
 
+	var detectLeaks bool
+	var mapSeed string
+	var reportVarMutation bool
+	var skipInits []string
+
 	var debugWithDlv bool
 	var xgoHome string
 
@@ -211,6 +225,39 @@ func parseOptions(args []string) (*options, error) {
 			noSetup = true
 			continue
 		}
+		if arg == "--detect-leaks" {
+			detectLeaks = true
+			continue
+		}
+		if arg == "--report-var-mutation" {
+			reportVarMutation = true
+			continue
+		}
+		if arg == "--global-deterministic-maps" {
+			mapSeed = "0"
+			continue
+		}
+		if strings.HasPrefix(arg, "--global-deterministic-maps=") {
+			mapSeed = strings.TrimPrefix(arg, "--global-deterministic-maps=")
+			if _, err := strconv.ParseUint(mapSeed, 10, 64); err != nil {
+				return nil, fmt.Errorf("--global-deterministic-maps: invalid seed %q, expect a non-negative number", mapSeed)
+			}
+			continue
+		}
+		var skipInit string
+		ok, err := flag.TryParseFlagValue("--skip-init", &skipInit, &i, args)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			for _, pattern := range strings.Split(skipInit, ",") {
+				if pattern == "" {
+					return nil, fmt.Errorf("--skip-init: invalid pattern %q", skipInit)
+				}
+				skipInits = append(skipInits, pattern)
+			}
+			continue
+		}
 		if isDevelopment && arg == "--debug-with-dlv" {
 			debugWithDlv = true
 			continue
@@ -279,12 +326,16 @@ func parseOptions(args []string) (*options, error) {
 		logCompile: logCompile,
 		logDebug:   logDebug,
 
-		noBuildOutput:   noBuildOutput,
-		noInstrument:    noInstrument,
-		resetInstrument: resetInstrument,
-		noSetup:         noSetup,
-		debugWithDlv:    debugWithDlv,
-		xgoHome:         xgoHome,
+		noBuildOutput:     noBuildOutput,
+		noInstrument:      noInstrument,
+		resetInstrument:   resetInstrument,
+		noSetup:           noSetup,
+		detectLeaks:       detectLeaks,
+		mapSeed:           mapSeed,
+		reportVarMutation: reportVarMutation,
+		skipInits:         skipInits,
+		debugWithDlv:      debugWithDlv,
+		xgoHome:           xgoHome,
 
 		syncXgoOnly:   syncXgoOnly,
 		setupDev:      setupDev,
@@ -13,6 +13,31 @@ const RuntimeProcGoroutineCreatedPatch = `for _, fn := range __xgo_on_gonewproc_
 return newg
 `
 
+// added at the beginning of newproc, go1.18 and above.
+// fn wraps the call of go statement with args evaluated
+const RuntimeProcGoStatementPatch = `if __xgo_go_hook != nil {
+	if gp := getg(); gp == gp.m.curg && __xgo_go_hook(getcallerpc(), *(*func())(unsafe.Pointer(&fn))) {
+		return
+	}
+}`
+
+// added to schedinit after alginit
+const RuntimeProcInitDeterministicMapsPatch = `__xgo_init_deterministic_maps()`
+
+// added to schedinit after goenvs
+const RuntimeProcInitDeterministicMapsAfterGoenvsPatch = `__xgo_init_deterministic_maps_after_goenvs()`
+
+// added after h.hash0 is randomized
+const RuntimeMapHashSeedPatch = `if __xgo_deterministic_maps {
+	h.hash0 = __xgo_map_seed
+}`
+
+// added before it.startBucket is derived from r,
+// so a map is always iterated from the same position
+const RuntimeMapIterStartPatch = `if __xgo_deterministic_maps {
+	r = uintptr(__xgo_map_mix(uint64(h.hash0)))
+}`
+
 // added after goroutine exit1
 const RuntimeProcGoroutineExitPatch = `for _, fn := range __xgo_on_goexits {
 	fn()
@@ -17,8 +17,17 @@ func __xgo_init_finished() bool
 func __xgo_on_init_finished(fn func())
 func __xgo_on_gonewproc(fn func(g uintptr))
 func __xgo_on_goexit(fn func())
+func __xgo_set_go_hook(hook func(pc uintptr, fn func()) bool)
+func __xgo_get_goroutine_creation(gp uintptr) (gopc uintptr, startpc uintptr)
+func __xgo_init_deterministic_maps()
+func __xgo_init_deterministic_maps_after_goenvs()
+func __xgo_argv_getenv(key string) string
+func __xgo_setup_deterministic_maps(s string)
+func __xgo_map_mix(z uint64) uint64
 func __xgo_on_test_start(fn interface{})
 func __xgo_get_test_starts() []interface{}
+func __xgo_on_init_skipped(pkgPath string, file string, line int, fn func())
+func __xgo_get_skipped_inits(f func(pkgPath string, file string, line int, fn func()))
 func __xgo_peek_panic() interface{}
 func __xgo_mem_equal(a, b unsafe.Pointer, size uintptr) bool
 func __xgo_get_pc_name(pc uintptr) string`
\ No newline at end of file
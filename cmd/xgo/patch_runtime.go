@@ -15,6 +15,7 @@ import (
 var xgoAutoGenRegisterFuncHelper = _FilePath{"src", "runtime", "__xgo_autogen_register_func_helper.go"}
 var xgoTrap = _FilePath{"src", "runtime", "xgo_trap.go"}
 var runtimeProc = _FilePath{"src", "runtime", "proc.go"}
+var runtimeMap = _FilePath{"src", "runtime", "map.go"}
 var runtimeTime _FilePath = _FilePath{"src", "runtime", "time.go"}
 var timeSleep _FilePath = _FilePath{"src", "time", "sleep.go"}
 
@@ -52,13 +53,18 @@ var runtimeFiles = []_FilePath{
 	xgoAutoGenRegisterFuncHelper,
 	xgoTrap,
 	runtimeProc,
+	runtimeMap,
 	testingFilePatch.FilePath,
 	runtimeTime,
 	timeSleep,
 }
 
-func patchRuntimeAndTesting(goroot string) error {
-	err := patchRuntimeProc(goroot)
+func patchRuntimeAndTesting(goroot string, goVersion *goinfo.GoVersion) error {
+	err := patchRuntimeProc(goroot, goVersion)
+	if err != nil {
+		return err
+	}
+	err = patchRuntimeMap(goroot)
 	if err != nil {
 		return err
 	}
@@ -129,7 +135,7 @@ func addRuntimeFunctions(goroot string, goVersion *goinfo.GoVersion, xgoSrc stri
 	return true, os.WriteFile(dstFile, content, 0755)
 }
 
-func patchRuntimeProc(goroot string) error {
+func patchRuntimeProc(goroot string, goVersion *goinfo.GoVersion) error {
 	procFile := filepath.Join(goroot, filepath.Join(runtimeProc...))
 	anchors := []string{
 		"func main() {",
@@ -156,6 +162,27 @@ func patchRuntimeProc(goroot string) error {
 			"return newg",
 			patch.RuntimeProcGoroutineCreatedPatch,
 		)
+
+		content = addContentAfter(content,
+			"/*<begin init_deterministic_maps>*/", "/*<end init_deterministic_maps>*/",
+			[]string{"func schedinit() {", "alginit()", "\n"},
+			patch.RuntimeProcInitDeterministicMapsPatch,
+		)
+		content = addContentAfter(content,
+			"/*<begin init_deterministic_maps_after_goenvs>*/", "/*<end init_deterministic_maps_after_goenvs>*/",
+			[]string{"func schedinit() {", "goenvs()", "\n"},
+			patch.RuntimeProcInitDeterministicMapsAfterGoenvsPatch,
+		)
+
+		// before go1.18, args of go statement are
+		// passed to newproc separately
+		if goVersion.Major > 1 || goVersion.Minor >= 18 {
+			content = addContentAfter(content,
+				"/*<begin add_go_statement_hook>*/", "/*<end add_go_statement_hook>*/",
+				[]string{"func newproc(fn *funcval) {", "\n"},
+				patch.RuntimeProcGoStatementPatch,
+			)
+		}
 		return content, nil
 	})
 	if err != nil {
@@ -164,6 +191,49 @@ func patchRuntimeProc(goroot string) error {
 	return nil
 }
 
+// see --global-deterministic-maps
+func patchRuntimeMap(goroot string) error {
+	mapFile := filepath.Join(goroot, filepath.Join(runtimeMap...))
+	return editFile(mapFile, func(content string) (string, error) {
+		content = addContentAfter(content,
+			"/*<begin seed_makemap_small>*/", "/*<end seed_makemap_small>*/",
+			[]string{"\nfunc makemap_small(", "h.hash0 = ", "\n"},
+			patch.RuntimeMapHashSeedPatch,
+		)
+		content = addContentAfter(content,
+			"/*<begin seed_makemap>*/", "/*<end seed_makemap>*/",
+			[]string{"\nfunc makemap(", "h.hash0 = ", "\n"},
+			patch.RuntimeMapHashSeedPatch,
+		)
+		// newer versions reset the seed in mapclear
+		if mapClearResetsSeed(content) {
+			content = addContentAfter(content,
+				"/*<begin seed_mapclear>*/", "/*<end seed_mapclear>*/",
+				[]string{"\nfunc mapclear(", "h.hash0 = ", "\n"},
+				patch.RuntimeMapHashSeedPatch,
+			)
+		}
+		content = addContentAtIndex(content,
+			"/*<begin deterministic_map_iter>*/", "/*<end deterministic_map_iter>*/",
+			[]string{"\nfunc mapiterinit(", "it.startBucket = r & bucketMask(h.B)"}, 1, true,
+			patch.RuntimeMapIterStartPatch,
+		)
+		return content, nil
+	})
+}
+
+func mapClearResetsSeed(content string) bool {
+	idx := strings.Index(content, "\nfunc mapclear(")
+	if idx < 0 {
+		return false
+	}
+	body := content[idx+1:]
+	if end := strings.Index(body, "\nfunc "); end >= 0 {
+		body = body[:end]
+	}
+	return strings.Contains(body, "h.hash0 = ")
+}
+
 func patchRuntimeTesting(goroot string) error {
 	return testingFilePatch.Apply(goroot, nil)
 }
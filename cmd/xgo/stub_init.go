@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/xhd2015/xgo/support/cmd"
+)
+
+// stubInitDirective, in a _test.go file, declares a package
+// pattern whose init functions are skipped, same as --skip-init:
+//
+//	//xgo:stub-init github.com/acme/db/...
+const stubInitDirective = "//xgo:stub-init"
+
+// findStubInits collects patterns of stub-init directives from
+// _test.go files of the packages tested by `go test args...`,
+// listed by the go binary of goroot
+func findStubInits(goroot string, dir string, args []string) ([]string, error) {
+	pkgs, tags := testPackageArgs(args)
+	listArgs := []string{"list", "-e", "-f", `{{.Dir}}{{range .TestGoFiles}}|{{.}}{{end}}{{range .XTestGoFiles}}|{{.}}{{end}}`}
+	if tags != "" {
+		listArgs = append(listArgs, "-tags", tags)
+	}
+	listArgs = append(listArgs, pkgs...)
+	env, err := patchEnvWithGoroot(nil, goroot)
+	if err != nil {
+		return nil, err
+	}
+	output, err := cmd.Env(env).Dir(dir).Output(filepath.Join(goroot, "bin", "go"), listArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("list test files: %w", err)
+	}
+	var patterns []string
+	for _, line := range strings.Split(output, "\n") {
+		files := strings.Split(strings.TrimSpace(line), "|")
+		for _, file := range files[1:] {
+			filePatterns, err := parseStubInits(filepath.Join(files[0], file))
+			if err != nil {
+				return nil, err
+			}
+			patterns = append(patterns, filePatterns...)
+		}
+	}
+	return patterns, nil
+}
+
+// flags of go test and build taking a value,
+// which can be given as the next argument
+var goTestValueFlags = map[string]bool{
+	"C": true, "o": true, "p": true, "exec": true, "vet": true,
+	"asmflags": true, "buildmode": true, "compiler": true, "gccgoflags": true, "gcflags": true,
+	"installsuffix": true, "ldflags": true, "mod": true, "modfile": true, "overlay": true,
+	"pgo": true, "pkgdir": true, "tags": true, "toolexec": true,
+	"bench": true, "benchtime": true, "blockprofile": true, "blockprofilerate": true,
+	"count": true, "covermode": true, "coverpkg": true, "coverprofile": true, "cpu": true,
+	"cpuprofile": true, "fuzz": true, "fuzzminimizetime": true, "fuzztime": true, "list": true,
+	"memprofile": true, "memprofilerate": true, "mutexprofile": true, "mutexprofilefraction": true,
+	"outputdir": true, "parallel": true, "run": true, "shuffle": true, "skip": true,
+	"timeout": true, "trace": true,
+}
+
+// testPackageArgs extracts package arguments and
+// the value of -tags from arguments of go test
+func testPackageArgs(args []string) (pkgs []string, tags string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "-args" || arg == "--args" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			pkgs = append(pkgs, arg)
+			continue
+		}
+		name := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		name = strings.TrimPrefix(name, "test.")
+		var value string
+		if idx := strings.Index(name, "="); idx >= 0 {
+			name, value = name[:idx], name[idx+1:]
+		} else if goTestValueFlags[name] && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		if name == "tags" {
+			tags = value
+		}
+	}
+	return pkgs, tags
+}
+
+func parseStubInits(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	// allow long lines, for example embedded data
+	scanner.Buffer(nil, 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(text, stubInitDirective) {
+			continue
+		}
+		rest := text[len(stubInitDirective):]
+		if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+			// other directive
+			continue
+		}
+		args := strings.Fields(rest)
+		if len(args) == 0 {
+			return nil, fmt.Errorf("%s:%d: %s requires package patterns", file, line, stubInitDirective)
+		}
+		patterns = append(patterns, args...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return patterns, nil
+}
+
+// skipInitsSum identifies a set of --skip-init patterns
+// in the name of build cache dir
+func skipInitsSum(patterns []string) string {
+	sorted := append([]string(nil), patterns...)
+	sort.Strings(sorted)
+	h := md5.New()
+	h.Write([]byte(strings.Join(sorted, ",")))
+	return hex.EncodeToString(h.Sum(nil))[:8]
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// go test -run TestFindStubInitsOfTestedPackages -v ./cmd/xgo
+func TestFindStubInitsOfTestedPackages(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":             "module example.com/demo\n\ngo 1.18\n",
+		"a/a.go":             "package a\n\n//xgo:stub-init github.com/acme/cache\n",
+		"a/a_test.go":        "package a\n\n//xgo:stub-init github.com/acme/db/...\n",
+		"b/b_test.go":        "package b\n\n//xgo:stub-init github.com/acme/mq\n",
+		"nested/go.mod":      "module example.com/nested\n\ngo 1.18\n",
+		"nested/n/n_test.go": "package n\n\n//xgo:stub-init github.com/acme/log\n",
+	}
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	goroot, err := getGoEnvRoot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		args   []string
+		expect []string
+	}{
+		{[]string{"-run", "TestX", "-v", "./a"}, []string{"github.com/acme/db/..."}},
+		{[]string{"./..."}, []string{"github.com/acme/db/...", "github.com/acme/mq"}},
+		{[]string{"-count=1", "./b", "-args", "./a"}, []string{"github.com/acme/mq"}},
+	}
+	for _, tc := range testCases {
+		patterns, err := findStubInits(goroot, dir, tc.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(patterns, tc.expect) {
+			t.Fatalf("args %v: expect patterns %v, actual: %v", tc.args, tc.expect, patterns)
+		}
+	}
+}
@@ -2,9 +2,9 @@ package main
 
 import "fmt"
 
-const VERSION = "1.0.24"
-const REVISION = "70a2950e60bbb075af333afa7a702fc02b9d55ed+1"
-const NUMBER = 182
+const VERSION = "1.0.25"
+const REVISION = "5df07c2debb0c9e27cf68fb4b3c6aaaafa504fce+1"
+const NUMBER = 184
 
 func getRevision() string {
 	revSuffix := ""
@@ -11,6 +11,17 @@ var stdWhitelist = map[string]map[string]bool{
 		"OpenFile":  true,
 		"ReadFile":  true,
 		"WriteFile": true,
+		// for overlay created by mock/fsfake
+		"Stat":      true,
+		"Lstat":     true,
+		"Remove":    true,
+		"RemoveAll": true,
+		"Mkdir":     true,
+		"MkdirAll":  true,
+		"ReadDir":   true,
+		// for env overlay created by mock.Setenv
+		"LookupEnv": true,
+		"Environ":   true,
 	},
 	"io": map[string]bool{
 		"ReadAll": true,
@@ -29,12 +40,24 @@ var stdWhitelist = map[string]map[string]bool{
 		"Sleep":       true, // NOTE: time.Sleep links to runtime.timeSleep
 		"NewTicker":   true,
 		"Time.Format": true,
+		"After":       true,
+		"Since":       true,
+		"NewTimer":    true,
+		"AfterFunc":   true,
+		// for fake timers created by mock/clock
+		"(*Timer).Stop":   true,
+		"(*Timer).Reset":  true,
+		"(*Ticker).Stop":  true,
+		"(*Ticker).Reset": true,
 	},
 	"os/exec": map[string]bool{
 		"Command":       true,
 		"(*Cmd).Run":    true,
 		"(*Cmd).Output": true,
 		"(*Cmd).Start":  true,
+
+		"(*Cmd).CombinedOutput": true,
+		"(*Cmd).Wait":           true,
 	},
 	"net/http": map[string]bool{
 		"Get":  true,
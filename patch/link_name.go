@@ -38,8 +38,12 @@ var linkMap = map[string]string{
 	"__xgo_link_on_init_finished":             "__xgo_on_init_finished",
 	"__xgo_link_on_gonewproc":                 "__xgo_on_gonewproc",
 	"__xgo_link_on_goexit":                    "__xgo_on_goexit",
+	"__xgo_link_get_goroutine_creation":       "__xgo_get_goroutine_creation",
+	"__xgo_link_set_go_hook":                  "__xgo_set_go_hook",
 	"__xgo_link_on_test_start":                xgoOnTestStart,
 	"__xgo_link_get_test_starts":              "__xgo_get_test_starts",
+	xgo_syntax.XgoLinkOnInitSkipped:           "__xgo_on_init_skipped",
+	"__xgo_link_get_skipped_inits":            "__xgo_get_skipped_inits",
 	"__xgo_link_retrieve_all_funcs_and_clear": "__xgo_retrieve_all_funcs_and_clear",
 	"__xgo_link_peek_panic":                   "__xgo_peek_panic",
 	"__xgo_link_mem_equal":                    "__xgo_mem_equal",
@@ -58,7 +62,7 @@ func isLinkValid(fnName string, targetName string, pkgPath string) bool {
 	if disableXgoLink {
 		return false
 	}
-	safeGenerated := (fnName == xgo_syntax.XgoLinkGeneratedRegisterFunc || fnName == xgo_syntax.XgoLinkTrapForGenerated || fnName == xgo_ctxt.XgoLinkTrapVarForGenerated)
+	safeGenerated := (fnName == xgo_syntax.XgoLinkGeneratedRegisterFunc || fnName == xgo_syntax.XgoLinkTrapForGenerated || fnName == xgo_ctxt.XgoLinkTrapVarForGenerated || fnName == xgo_syntax.XgoLinkOnInitSkipped)
 	if safeGenerated {
 		// generated by xgo on the fly for every instrumented package
 		return true
@@ -54,6 +54,10 @@ func __xgo_link_trap_var_for_generated(pkgPath string, name string, tmpVarAddr i
 	// linked by compiler
 }
 
+func __xgo_link_on_init_skipped(pkgPath string, file string, line int, fn func()) {
+	// linked by compiler
+}
+
 func __xgo_link_generated_register_func(fn interface{}) {
 	// linked later by compiler
 	panic("failed to link __xgo_link_generated_register_func")
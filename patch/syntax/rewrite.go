@@ -126,6 +126,13 @@ func rewriteStdAndGenericFuncs(funcDecls []*DeclInfo, pkgPath string) {
 		argValues := getRefSlice(pos, fnTypeCopy.ParamList)
 		argAddrs := getRefAddrSlice(pos, fnTypeCopy.ParamList)
 		resultAddrs := getRefAddrSlice(pos, fnTypeCopy.ResultList)
+		if fn.Generic {
+			// the last arg carries type args of the instantiation,
+			// see trapFunc in runtime/trap
+			if typeArgs := getTypeArgsSlice(pos, newDecl); typeArgs != nil {
+				argAddrs.ElemList = append(argAddrs.ElemList, typeArgs)
+			}
+		}
 
 		var hasDots bool
 		if len(fnTypeCopy.ParamList) > 0 {
@@ -225,6 +232,72 @@ func rewriteStdAndGenericFuncs(funcDecls []*DeclInfo, pkgPath string) {
 	}
 }
 
+// getTypeArgsSlice returns []interface{}{(*T1)(nil), (*T2)(nil)},
+// whose dynamic types are resolved per instantiation, or nil if
+// any type param is blank
+func getTypeArgsSlice(pos syntax.Pos, decl *syntax.FuncDecl) *syntax.CompositeLit {
+	var names []string
+	if decl.Recv != nil {
+		names = getRecvTypeParamNames(decl.Recv.Type)
+	} else {
+		for _, tparam := range decl.TParamList {
+			names = append(names, tparam.Name.Value)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	elems := make([]syntax.Expr, len(names))
+	for i, name := range names {
+		if isBlankName(name) {
+			return nil
+		}
+		elems[i] = &syntax.CallExpr{
+			Fun: &syntax.ParenExpr{
+				X: &syntax.Operation{
+					Op: syntax.Mul,
+					X:  syntax.NewName(pos, name),
+				},
+			},
+			ArgList: []syntax.Expr{syntax.NewName(pos, "nil")},
+		}
+	}
+	return &syntax.CompositeLit{
+		Type: &syntax.SliceType{
+			Elem: &syntax.InterfaceType{},
+		},
+		ElemList: elems,
+		Rbrace:   pos,
+	}
+}
+
+// getRecvTypeParamNames returns [T,U] of receiver List[T,U] or *List[T,U]
+func getRecvTypeParamNames(recvType syntax.Expr) []string {
+	if op, ok := recvType.(*syntax.Operation); ok && op.Op == syntax.Mul && op.Y == nil {
+		recvType = op.X
+	}
+	if paren, ok := recvType.(*syntax.ParenExpr); ok {
+		return getRecvTypeParamNames(paren.X)
+	}
+	indexExpr, ok := recvType.(*syntax.IndexExpr)
+	if !ok {
+		return nil
+	}
+	index := []syntax.Expr{indexExpr.Index}
+	if list, ok := indexExpr.Index.(*syntax.ListExpr); ok {
+		index = list.ElemList
+	}
+	names := make([]string, 0, len(index))
+	for _, expr := range index {
+		name, ok := expr.(*syntax.Name)
+		if !ok {
+			return nil
+		}
+		names = append(names, name.Value)
+	}
+	return names
+}
+
 func makeEmptyCallStmt(callExpr *syntax.CallExpr) syntax.Stmt {
 	return &syntax.ExprStmt{
 		X: callExpr,
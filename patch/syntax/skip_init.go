@@ -0,0 +1,83 @@
+package syntax
+
+import (
+	"cmd/compile/internal/syntax"
+	"os"
+	"strings"
+
+	xgo_ctxt "cmd/compile/internal/xgo_rewrite_internal/patch/ctxt"
+)
+
+// XGO_SKIP_INIT is set by `xgo test --skip-init`, a comma
+// separated list of package patterns
+const XGO_SKIP_INIT = "XGO_SKIP_INIT"
+
+const XgoLinkOnInitSkipped = "__xgo_link_on_init_skipped"
+
+var skipInitPatterns = splitSkipInitPatterns(os.Getenv(XGO_SKIP_INIT))
+
+func splitSkipInitPatterns(env string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(env, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+func shouldSkipInit(pkgPath string) bool {
+	for _, pattern := range skipInitPatterns {
+		if xgo_ctxt.MatchPkgPattern(pkgPath, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipInits rewrites every init function of the package:
+//
+//	func init() { BODY }
+//
+// into
+//
+//	func init() { __xgo_link_on_init_skipped(pkgPath, file, line, func() { BODY }) }
+//
+// so the runtime records BODY instead of running it
+func skipInits(pkgPath string, fileList []*syntax.File) {
+	if !shouldSkipInit(pkgPath) {
+		return
+	}
+	for _, f := range fileList {
+		file := f.Pos().RelFilename()
+		for _, decl := range f.DeclList {
+			fn, ok := decl.(*syntax.FuncDecl)
+			if !ok || fn.Recv != nil || fn.Name.Value != "init" || fn.Body == nil {
+				continue
+			}
+			pos := fn.Pos()
+			body := &syntax.BlockStmt{
+				List: []syntax.Stmt{
+					&syntax.ExprStmt{
+						X: &syntax.CallExpr{
+							Fun: syntax.NewName(pos, XgoLinkOnInitSkipped),
+							ArgList: []syntax.Expr{
+								newStringLit(pkgPath),
+								newStringLit(file),
+								newIntLit(int(pos.Line())),
+								&syntax.FuncLit{
+									Type: &syntax.FuncType{},
+									Body: fn.Body,
+								},
+							},
+						},
+					},
+				},
+				Rbrace: fn.Body.Rbrace,
+			}
+			body.SetPos(fn.Body.Pos())
+			fn.Body = body
+		}
+	}
+}
@@ -227,6 +227,9 @@ func registerFuncs(fileList []*syntax.File, addFile func(name string, r io.Reade
 		// syntax.Fdump(os.Stderr, fileList[0])
 	}
 
+	// xgo test --skip-init
+	skipInits(pkgPath, fileList)
+
 	// always generate a helper to aid IR
 	helperFile := addFile("__xgo_autogen_register_func_helper.go", strings.NewReader(generateRegHelperCode(pkgName)))
 
@@ -689,7 +692,10 @@ func generateFuncRegBody(funcDecls []*DeclInfo, xgoRegFunc string, xgoLocalFuncS
 		}
 		var fnRefName string = "nil"
 		var varRefName string = "nil"
-		if funcDecl.Kind.IsFunc() {
+		if funcDecl.Interface {
+			// a typed nil pointer to retrieve the interface's type at runtime
+			varRefName = "(*" + funcDecl.RecvTypeName + ")(nil)"
+		} else if funcDecl.Kind.IsFunc() {
 			if !funcDecl.Generic {
 				fnRefName = funcDecl.RefName()
 			}
@@ -127,6 +127,105 @@ func __xgo_on_goexit(fn func()) {
 	__xgo_on_goexits = append(__xgo_on_goexits, fn)
 }
 
+// called by newproc for every go statement, returns
+// true if the go statement is taken over by hook
+var __xgo_go_hook func(pc uintptr, fn func()) bool
+
+func __xgo_set_go_hook(hook func(pc uintptr, fn func()) bool) {
+	__xgo_go_hook = hook
+}
+
+// returns pc of the go statement that created goroutine gp,
+// and entry pc of the goroutine's function
+func __xgo_get_goroutine_creation(gp uintptr) (gopc uintptr, startpc uintptr) {
+	newg := (*g)(unsafe.Pointer(gp))
+	return newg.gopc, newg.startpc
+}
+
+// deterministic maps: hash keys, map seeds and iteration
+// start are derived from XGO_DETERMINISTIC_MAPS, for every
+// map of the process, including those of runtime and std.
+// All maps share the same seed, which is read only after
+// init, so the iteration order of a map only depends on
+// its keys, not on how many maps are created before it
+// or by which goroutine.
+var __xgo_deterministic_maps bool
+var __xgo_map_seed uint32
+
+// envs are passed in argv on these systems, so they can
+// be read before goenvs
+const __xgo_envs_in_argv = GOOS != "windows" && GOOS != "plan9" && GOOS != "js" && GOOS != "wasip1"
+
+// called by schedinit right after alginit, before any
+// map is created, so that no existing map is hashed with
+// the random keys replaced here
+func __xgo_init_deterministic_maps() {
+	if !__xgo_envs_in_argv {
+		return
+	}
+	__xgo_setup_deterministic_maps(__xgo_argv_getenv("XGO_DETERMINISTIC_MAPS"))
+}
+
+// called by schedinit right after goenvs, for systems not
+// passing envs in argv. No map is created before it on these
+// systems, typelinksinit only creates one for shared modules
+func __xgo_init_deterministic_maps_after_goenvs() {
+	if __xgo_envs_in_argv {
+		return
+	}
+	__xgo_setup_deterministic_maps(gogetenv("XGO_DETERMINISTIC_MAPS"))
+}
+
+// same as goenvs_unix, but only for key
+func __xgo_argv_getenv(key string) string {
+	n := int32(0)
+	for argv_index(argv, argc+1+n) != nil {
+		n++
+	}
+	for i := int32(0); i < n; i++ {
+		s := gostringnocopy(argv_index(argv, argc+1+i))
+		if len(s) > len(key) && s[len(key)] == '=' && s[:len(key)] == key {
+			return s[len(key)+1:]
+		}
+	}
+	return ""
+}
+
+func __xgo_setup_deterministic_maps(s string) {
+	if s == "" {
+		return
+	}
+	var seed uint64
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			print("WARNING: invalid XGO_DETERMINISTIC_MAPS: ", s, ", expect a number\n")
+			return
+		}
+		seed = seed*10 + uint64(s[i]-'0')
+	}
+
+	// replace the random keys set by alginit
+	state := seed
+	for i := range aeskeysched {
+		state += 0x9e3779b97f4a7c15
+		aeskeysched[i] = byte(__xgo_map_mix(state))
+	}
+	for i := range hashkey {
+		state += 0x9e3779b97f4a7c15
+		hashkey[i] = uintptr(__xgo_map_mix(state)) | 1
+	}
+	state += 0x9e3779b97f4a7c15
+	__xgo_map_seed = uint32(__xgo_map_mix(state))
+	__xgo_deterministic_maps = true
+}
+
+// splitmix64 finalizer
+func __xgo_map_mix(z uint64) uint64 {
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
 var __xgo_on_test_starts []interface{} // func(t *testing.T,fn func(t *testing.T))
 
 func __xgo_on_test_start(fn interface{}) {
@@ -137,6 +236,28 @@ func __xgo_get_test_starts() []interface{} {
 	return __xgo_on_test_starts
 }
 
+type __xgo_skipped_init struct {
+	pkgPath string
+	file    string
+	line    int
+	fn      func()
+}
+
+// init functions skipped by xgo test --skip-init,
+// in the order they would have run
+var __xgo_skipped_inits []__xgo_skipped_init
+
+func __xgo_on_init_skipped(pkgPath string, file string, line int, fn func()) {
+	print("xgo: skipped init of ", pkgPath, " at ", file, ":", line, "\n")
+	__xgo_skipped_inits = append(__xgo_skipped_inits, __xgo_skipped_init{pkgPath: pkgPath, file: file, line: line, fn: fn})
+}
+
+func __xgo_get_skipped_inits(f func(pkgPath string, file string, line int, fn func())) {
+	for _, s := range __xgo_skipped_inits {
+		f(s.pkgPath, s.file, s.line, s.fn)
+	}
+}
+
 // check gorecover() for implementation details
 func __xgo_peek_panic() interface{} {
 	gp := getg()
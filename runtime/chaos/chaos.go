@@ -0,0 +1,165 @@
+// Package chaos injects faults into functions matched by
+// package and function patterns: returned errors, added
+// latency and panics.
+//
+// Chaos is disabled unless env XGO_CHAOS is set, so the same
+// test suite can run in a separate chaos job:
+//
+//	XGO_CHAOS=1 xgo test ./...
+//
+// Faults are decided by a random generator whose seed is
+// printed when the test fails, set XGO_CHAOS_SEED to reproduce.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xhd2015/xgo/runtime/core"
+	"github.com/xhd2015/xgo/runtime/mock"
+	"github.com/xhd2015/xgo/runtime/trap"
+)
+
+// XGO_CHAOS enables fault injection, valid values: 1, true, on
+const XGO_CHAOS = "XGO_CHAOS"
+
+// XGO_CHAOS_SEED sets the seed of the random generator,
+// by default a time based seed is used
+const XGO_CHAOS_SEED = "XGO_CHAOS_SEED"
+
+// ErrInjected is the default injected error
+var ErrInjected = errors.New("chaos: injected error")
+
+// Rule describes faults injected into matched functions,
+// rates are probabilities between 0 and 1.
+type Rule struct {
+	// Pkg is a package path, or `path/...` to include
+	// all sub packages
+	Pkg string
+
+	// Func is a glob over identity names like `Func` and
+	// `(*Type).Method`, see mock.MockPattern
+	Func string
+
+	// ErrorRate only applies to functions returning error
+	ErrorRate float64
+	// Error is the injected error, default ErrInjected
+	Error error
+
+	LatencyRate float64
+	Latency     time.Duration
+
+	PanicRate float64
+}
+
+// Panic is the value of injected panics
+type Panic struct {
+	Func string
+}
+
+func (c *Panic) Error() string {
+	return "chaos: injected panic in " + c.Func
+}
+
+// Enabled reports whether env XGO_CHAOS is set
+func Enabled() bool {
+	switch os.Getenv(XGO_CHAOS) {
+	case "1", "true", "on":
+		return true
+	}
+	return false
+}
+
+// Enable injects faults according to `rules` if chaos
+// is enabled, otherwise it does nothing.
+// The scope is the same as mock.Mock. When the test
+// fails, the seed is printed.
+// The returned function can be used to stop injection.
+func Enable(t testing.TB, rules ...*Rule) func() {
+	if t == nil {
+		panic("t cannot be nil")
+	}
+	if !Enabled() {
+		return func() {}
+	}
+	seed := getSeed()
+	rnd := &random{rand: rand.New(rand.NewSource(seed))}
+	t.Cleanup(func() {
+		if t.Failed() {
+			t.Logf("chaos: seed %d, reproduce with %s=%d", seed, XGO_CHAOS_SEED, seed)
+		}
+	})
+
+	var cancels []func()
+	for _, rule := range rules {
+		if rule.ErrorRate+rule.PanicRate > 1 {
+			panic(fmt.Errorf("chaos: error rate plus panic rate should not exceed 1: %s %s", rule.Pkg, rule.Func))
+		}
+		cancels = append(cancels, mock.MockPattern(rule.Pkg, rule.Func, rule.interceptor(rnd)))
+	}
+	return func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
+}
+
+func (c *Rule) interceptor(rnd *random) mock.Interceptor {
+	return func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+		if c.Latency > 0 && rnd.hit(c.LatencyRate) {
+			trap.Direct(func() {
+				time.Sleep(c.Latency)
+			})
+		}
+		p := rnd.float64()
+		if p < c.PanicRate {
+			panic(&Panic{Func: fn.DisplayName()})
+		}
+		if fn.LastResultErr && p < c.PanicRate+c.ErrorRate {
+			err := c.Error
+			if err == nil {
+				err = ErrInjected
+			}
+			// returned as the function's error
+			return err
+		}
+		return mock.ErrCallOld
+	}
+}
+
+type random struct {
+	mutex sync.Mutex
+	rand  *rand.Rand
+}
+
+func (c *random) float64() float64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.rand.Float64()
+}
+
+func (c *random) hit(rate float64) bool {
+	return rate > 0 && c.float64() < rate
+}
+
+func getSeed() int64 {
+	if s := os.Getenv(XGO_CHAOS_SEED); s != "" {
+		seed, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			panic(fmt.Errorf("invalid %s: %s", XGO_CHAOS_SEED, s))
+		}
+		return seed
+	}
+	var seed int64
+	trap.Direct(func() {
+		seed = time.Now().UnixNano()
+	})
+	return seed
+}
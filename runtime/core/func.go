@@ -57,7 +57,7 @@ type FuncInfo struct {
 
 	PC   uintptr     `json:"-"`
 	Func interface{} `json:"-"`
-	Var  interface{} `json:"-"` // var address
+	Var  interface{} `json:"-"` // var address, or typed nil pointer for interface
 
 	RecvName string
 	ArgNames []string
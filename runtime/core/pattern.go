@@ -0,0 +1,113 @@
+package core
+
+import "strings"
+
+// This file is copied to patch/ctxt/match_gen.go by
+// `go run ./script/generate pattern-match`, so compile time
+// flags and runtime APIs share the same pattern language.
+
+// MatchPattern matches pattern in the form of {pkg}.{func}:
+//   - pkg is matched by MatchPkg
+//   - func is a glob over identity names, see MatchGlob
+//
+// A pattern without dot matches funcName exactly. The first dot
+// after the last slash separates pkg and func, so a package path
+// whose last element contains dots, e.g. gopkg.in/yaml.v3, can
+// only be matched by its package name.
+func MatchPattern(pkgPath string, pkgName string, funcName string, pattern string) bool {
+	pkgPattern, funcPattern, ok := splitPattern(pattern)
+	if !ok {
+		return funcName == pattern
+	}
+	if !MatchPkg(pkgPath, pkgName, pkgPattern) {
+		return false
+	}
+	return MatchGlob(funcName, funcPattern)
+}
+
+// MatchPkg matches a package by pattern, which can be:
+//   - the package name, only if pkgName is given
+//   - `*suffix` of package path
+//   - package path, or `path/...` to include sub packages
+//
+// NOTE: package names are not available at runtime,
+// runtime APIs pass empty pkgName.
+func MatchPkg(pkgPath string, pkgName string, pattern string) bool {
+	if pkgName != "" && pkgName == pattern {
+		return true
+	}
+	if strings.HasPrefix(pattern, "*") {
+		suffix := pattern[1:]
+		return suffix == "" || strings.HasSuffix(pkgPath, suffix)
+	}
+	return MatchPkgPattern(pkgPath, pattern)
+}
+
+// splitPattern splits at the first dot after
+// last slash, skipping `...`
+func splitPattern(pattern string) (pkgPattern string, funcPattern string, ok bool) {
+	base := strings.LastIndex(pattern, "/") + 1
+	if strings.HasPrefix(pattern[base:], "...") {
+		base += len("...")
+	}
+	dotIdx := strings.Index(pattern[base:], ".")
+	if dotIdx < 0 {
+		return "", "", false
+	}
+	dotIdx += base
+	return pattern[:dotIdx], pattern[dotIdx+1:], true
+}
+
+// MatchPkgPattern matches exact package path, or
+// all packages under `path` if pattern is `path/...`
+func MatchPkgPattern(pkgPath string, pattern string) bool {
+	if pattern == "..." {
+		return true
+	}
+	if strings.HasSuffix(pattern, "/...") {
+		prefix := pattern[:len(pattern)-len("/...")]
+		return pkgPath == prefix || strings.HasPrefix(pkgPath, prefix+"/")
+	}
+	return pkgPath == pattern
+}
+
+// MatchGlob matches name against pattern, where '*' matches
+// any sequence of characters, and '?' matches a single character.
+// As an exception, "(*" is treated literally so that "(*Client).*"
+// matches all methods of *Client.
+func MatchGlob(name string, pattern string) bool {
+	px, nx := 0, 0
+	// position to restart when mismatch after last '*'
+	nextPx, nextNx := -1, -1
+	for px < len(pattern) || nx < len(name) {
+		if px < len(pattern) {
+			c := pattern[px]
+			switch {
+			case c == '*' && (px == 0 || pattern[px-1] != '('):
+				nextPx = px
+				nextNx = nx + 1
+				px++
+				continue
+			case c == '?':
+				if nx < len(name) {
+					px++
+					nx++
+					continue
+				}
+			default:
+				if nx < len(name) && name[nx] == c {
+					px++
+					nx++
+					continue
+				}
+			}
+		}
+		if nextNx > 0 && nextNx <= len(name) {
+			px = nextPx
+			nx = nextNx
+			continue
+		}
+		return false
+	}
+	return true
+}
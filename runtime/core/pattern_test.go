@@ -0,0 +1,45 @@
+package core
+
+import "testing"
+
+// go test -run TestMatchPattern -v ./core
+func TestMatchPattern(t *testing.T) {
+	var testCases = []struct {
+		PkgPath  string
+		PkgName  string
+		FuncName string
+		Pattern  string
+		Match    bool
+	}{
+		// no dot: func name only
+		{"a/b", "b", "Run", "Run", true},
+		{"a/b", "b", "Run", "Stop", false},
+		// package name
+		{"a/b", "b", "Run", "b.Run", true},
+		{"a/b", "", "Run", "b.Run", false},
+		// package path, dots in path
+		{"github.com/acme/b", "b", "Run", "github.com/acme/b.Run", true},
+		{"gopkg.in/yaml.v3", "yaml", "Unmarshal", "gopkg.in/yaml.v3.Unmarshal", false},
+		// `*suffix` of package path
+		{"github.com/acme/b", "b", "Run", "*acme/b.Run", true},
+		{"github.com/acme/b", "b", "Run", "*.Run", true},
+		{"github.com/acme/b", "b", "Run", "*other/b.Run", false},
+		// sub packages
+		{"github.com/acme/b/c", "c", "Run", "github.com/acme/....Run", true},
+		{"github.com/acme", "acme", "Run", "github.com/acme/....Run", true},
+		{"github.com/acmex", "acmex", "Run", "github.com/acme/....Run", false},
+		// glob
+		{"a/b", "b", "Run", "b.*", true},
+		{"a/b", "b", "RunAll", "b.Run*", true},
+		{"a/b", "b", "Run", "b.R?n", true},
+		{"a/b", "b", "(*Client).Do", "b.(*Client).*", true},
+		{"a/b", "b", "Client.Do", "b.(*Client).*", false},
+		{"a/b", "b", "(*Client).Do", "b.*.Do", true},
+	}
+	for _, testCase := range testCases {
+		match := MatchPattern(testCase.PkgPath, testCase.PkgName, testCase.FuncName, testCase.Pattern)
+		if match != testCase.Match {
+			t.Fatalf("match %s %s %s with %q, expect %v, actual: %v", testCase.PkgPath, testCase.PkgName, testCase.FuncName, testCase.Pattern, testCase.Match, match)
+		}
+	}
+}
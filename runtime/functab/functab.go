@@ -120,6 +120,23 @@ func GetTypeMethods(typ reflect.Type) map[string]*core.FuncInfo {
 	return getTypeMethodMapping()[typ]
 }
 
+// GetTypesMethods returns methods of all registered types,
+// keyed by receiver type
+func GetTypesMethods() map[reflect.Type]map[string]*core.FuncInfo {
+	return getTypeMethodMapping()
+}
+
+// GetInterfaceType returns the type of the interface
+// declared in pkg, nil if not found
+func GetInterfaceType(pkg string, name string) reflect.Type {
+	intf := interfaceMapping[pkg][name]
+	if intf == nil || intf.Var == nil {
+		return nil
+	}
+	// Var is a typed nil pointer to the interface
+	return reflect.TypeOf(intf.Var).Elem()
+}
+
 func getInterfaceOrGenericByFullName(fullName string) *core.FuncInfo {
 	pkgPath, recvName, recvPtr, typeGeneric, funcGeneric, funcName := core.ParseFuncName(fullName)
 	if typeGeneric != "" || funcGeneric != "" {
@@ -0,0 +1,20 @@
+// Package testhook holds the link to test start shared
+// by runtime packages, instead of each declaring its own stub
+package testhook
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+// link by compiler
+func __xgo_link_on_test_start(fn func(t *testing.T, fn func(t *testing.T))) {
+	fmt.Fprintln(os.Stderr, "WARNING: failed to link __xgo_link_on_test_start(requires xgo).")
+}
+
+// OnTestStart registers `fn` to be called at the
+// start of each test and subtest, in the test's goroutine
+func OnTestStart(fn func(t *testing.T, fn func(t *testing.T))) {
+	__xgo_link_on_test_start(fn)
+}
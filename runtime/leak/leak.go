@@ -0,0 +1,331 @@
+// Package leak reports goroutines started by a test that
+// are still running when the test finishes.
+//
+// Goroutines are tracked with xgo's goroutine hooks, so a
+// goroutine is attributed to the test whose goroutine, or
+// descendant goroutine, started it. Unlike stack scraping,
+// this is precise and works with t.Parallel.
+//
+// Check a single test:
+//
+//	func TestServer(t *testing.T) {
+//		leak.Check(t)
+//		...
+//	}
+//
+// Or check all tests with:
+//
+//	xgo test --detect-leaks ./...
+//
+// which only checks packages whose test binary links this
+// package, e.g. imported by any test file of the package:
+//
+//	import _ "github.com/xhd2015/xgo/runtime/leak"
+package leak
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/xhd2015/xgo/runtime/core"
+	"github.com/xhd2015/xgo/runtime/functab"
+	"github.com/xhd2015/xgo/runtime/internal/testhook"
+	"github.com/xhd2015/xgo/runtime/trap"
+)
+
+// XGO_DETECT_LEAKS is set by `xgo test --detect-leaks`,
+// valid values: 1, true, on
+const XGO_DETECT_LEAKS = "XGO_DETECT_LEAKS"
+
+// how long to wait for goroutines to exit
+// after the test finishes
+const maxWait = time.Second
+const pollInterval = 10 * time.Millisecond
+
+// Goroutine is a goroutine started under a test
+type Goroutine struct {
+	// Test is the name of the test
+	Test string
+
+	// Func is the function the goroutine runs
+	Func string
+
+	// Creator is the function containing the go statement
+	Creator string
+	File    string
+	Line    int
+
+	// CreatorInfo is nil if the creator is not registered by xgo
+	CreatorInfo *core.FuncInfo
+
+	// Parent is the goroutine that started this one,
+	// nil if started by the test itself
+	Parent *Goroutine
+
+	gopc     uintptr
+	startpc  uintptr
+	test     *testRecord
+	reported bool
+	// resolved lazily because creation hook runs on system stack
+	resolveOnce sync.Once
+}
+
+type testRecord struct {
+	name   string
+	parent *testRecord
+
+	mutex   sync.Mutex
+	checked bool
+}
+
+var alive sync.Map             // goroutine key -> *Goroutine
+var testGoroutines sync.Map    // goroutine key -> *testRecord
+var testRecordMapping sync.Map // *testing.T -> *testRecord
+
+var mutex sync.Mutex
+var ignored = make(map[string]bool)          // function names
+var ignoredPrefixes = []string{"os/signal."} // started once per process
+
+// link by compiler
+func __xgo_link_on_gonewproc(f func(g uintptr)) {
+	fmt.Fprintln(os.Stderr, "WARNING: failed to link __xgo_link_on_gonewproc(requires xgo).")
+}
+func __xgo_link_on_goexit(fn func()) {
+	fmt.Fprintln(os.Stderr, "WARNING: failed to link __xgo_link_on_goexit(requires xgo).")
+}
+func __xgo_link_getcurg() unsafe.Pointer {
+	fmt.Fprintln(os.Stderr, "WARNING: failed to link __xgo_link_getcurg(requires xgo).")
+	return nil
+}
+func __xgo_link_get_goroutine_creation(g uintptr) (gopc uintptr, startpc uintptr) {
+	fmt.Fprintln(os.Stderr, "WARNING: failed to link __xgo_link_get_goroutine_creation(requires xgo).")
+	return 0, 0
+}
+
+func init() {
+	detect := Enabled()
+	testhook.OnTestStart(func(t *testing.T, fn func(t *testing.T)) {
+		key := uintptr(__xgo_link_getcurg())
+
+		var parent *testRecord
+		if val, ok := alive.Load(key); ok {
+			// the goroutine of a subtest is not a leak
+			alive.Delete(key)
+			parent = val.(*Goroutine).test
+		}
+
+		record := &testRecord{name: t.Name(), parent: parent}
+		testGoroutines.Store(key, record)
+		testRecordMapping.Store(t, record)
+		t.Cleanup(func() {
+			testRecordMapping.Delete(t)
+		})
+		if detect {
+			Check(t)
+		}
+	})
+	__xgo_link_on_gonewproc(func(newg uintptr) {
+		key := uintptr(__xgo_link_getcurg())
+		var record *testRecord
+		var parent *Goroutine
+		if val, ok := alive.Load(key); ok {
+			parent = val.(*Goroutine)
+			record = parent.test
+		} else {
+			val, ok := testGoroutines.Load(key)
+			if !ok {
+				return
+			}
+			record = val.(*testRecord)
+		}
+		gopc, startpc := __xgo_link_get_goroutine_creation(newg)
+		g := &Goroutine{
+			Test:    record.name,
+			Parent:  parent,
+			gopc:    gopc,
+			startpc: startpc,
+			test:    record,
+		}
+		alive.Store(newg, g)
+	})
+	__xgo_link_on_goexit(func() {
+		key := uintptr(__xgo_link_getcurg())
+		testGoroutines.Delete(key)
+		alive.Delete(key)
+	})
+}
+
+// Enabled reports whether env XGO_DETECT_LEAKS is set
+func Enabled() bool {
+	switch os.Getenv(XGO_DETECT_LEAKS) {
+	case "1", "true", "on":
+		return true
+	}
+	return false
+}
+
+// Ignore excludes goroutines running any of `funcs` from
+// being reported, names are full names like `pkg.(*T).loop`.
+// It is usually called from init.
+func Ignore(funcs ...string) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	for _, fn := range funcs {
+		ignored[fn] = true
+	}
+}
+
+// Check reports goroutines started under `t` and its
+// subtests that are still running when `t` finishes,
+// after waiting a while for them to exit.
+// It requires `t` to be started by xgo test.
+func Check(t testing.TB) {
+	if t == nil {
+		panic("t cannot be nil")
+	}
+	val, ok := testRecordMapping.Load(t)
+	if !ok {
+		panic(fmt.Errorf("leak: test not tracked: %s, requires xgo test", t.Name()))
+	}
+	record := val.(*testRecord)
+	record.mutex.Lock()
+	checked := record.checked
+	record.checked = true
+	record.mutex.Unlock()
+	if checked {
+		return
+	}
+	t.Cleanup(func() {
+		leaks := markReported(waitLeaks(record))
+		if len(leaks) == 0 {
+			return
+		}
+		t.Errorf("leak: %d goroutine(s) still running:\n%s", len(leaks), Format(leaks))
+	})
+}
+
+// Leaks returns goroutines started under `t` and its
+// subtests that are still running and not yet reported
+// by Check, without waiting.
+func Leaks(t testing.TB) []*Goroutine {
+	val, ok := testRecordMapping.Load(t)
+	if !ok {
+		return nil
+	}
+	return getLeaks(val.(*testRecord))
+}
+
+// Format formats goroutines with their creation trace
+func Format(goroutines []*Goroutine) string {
+	var b strings.Builder
+	for i, g := range goroutines {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		g.resolve()
+		fmt.Fprintf(&b, "  %s\n", g.Func)
+		for c := g; c != nil; c = c.Parent {
+			c.resolve()
+			fmt.Fprintf(&b, "      created by %s at %s:%d\n", c.Creator, c.File, c.Line)
+		}
+		fmt.Fprintf(&b, "      in test %s\n", g.Test)
+	}
+	return b.String()
+}
+
+func waitLeaks(record *testRecord) []*Goroutine {
+	var leaks []*Goroutine
+	trap.Direct(func() {
+		deadline := time.Now().Add(maxWait)
+		for {
+			leaks = getLeaks(record)
+			if len(leaks) == 0 || time.Now().After(deadline) {
+				return
+			}
+			time.Sleep(pollInterval)
+		}
+	})
+	return leaks
+}
+
+// markReported filters out goroutines reported
+// by subtests concurrently
+func markReported(leaks []*Goroutine) []*Goroutine {
+	mutex.Lock()
+	defer mutex.Unlock()
+	var list []*Goroutine
+	for _, g := range leaks {
+		if g.reported {
+			continue
+		}
+		g.reported = true
+		list = append(list, g)
+	}
+	return list
+}
+
+func getLeaks(record *testRecord) []*Goroutine {
+	var list []*Goroutine
+	mutex.Lock()
+	alive.Range(func(key, val interface{}) bool {
+		g := val.(*Goroutine)
+		if !g.reported && g.test.under(record) {
+			list = append(list, g)
+		}
+		return true
+	})
+	mutex.Unlock()
+
+	leaks := make([]*Goroutine, 0, len(list))
+	for _, g := range list {
+		g.resolve()
+		if !isIgnored(g.Func) {
+			leaks = append(leaks, g)
+		}
+	}
+	return leaks
+}
+
+func isIgnored(fn string) bool {
+	mutex.Lock()
+	ignore := ignored[fn]
+	mutex.Unlock()
+	if ignore {
+		return true
+	}
+	for _, prefix := range ignoredPrefixes {
+		if strings.HasPrefix(fn, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *testRecord) under(record *testRecord) bool {
+	for r := c; r != nil; r = r.parent {
+		if r == record {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Goroutine) resolve() {
+	c.resolveOnce.Do(func() {
+		if fn := runtime.FuncForPC(c.startpc); fn != nil {
+			c.Func = fn.Name()
+		}
+		// gopc is the return address of the go statement
+		if fn := runtime.FuncForPC(c.gopc); fn != nil {
+			c.Creator = fn.Name()
+			c.File, c.Line = fn.FileLine(c.gopc - 1)
+			c.CreatorInfo = functab.InfoPC(fn.Entry())
+		}
+	})
+}
@@ -0,0 +1,295 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+
+	"github.com/xhd2015/xgo/runtime/core"
+	"github.com/xhd2015/xgo/runtime/trace"
+	"github.com/xhd2015/xgo/runtime/trap"
+)
+
+// XGO_MOCK_CASSETTE_MODE controls UseCassette,
+// valid values: record, replay, passthrough.
+// Default: replay
+const XGO_MOCK_CASSETTE_MODE = "XGO_MOCK_CASSETTE_MODE"
+
+const (
+	CassetteMode_Record      = "record"
+	CassetteMode_Replay      = "replay"
+	CassetteMode_Passthrough = "passthrough"
+)
+
+type cassette struct {
+	Calls []*cassetteCall `json:"calls"`
+}
+
+type cassetteCall struct {
+	Func    string            `json:"func"`
+	Args    json.RawMessage   `json:"args"`
+	Results []json.RawMessage `json:"results"`
+	Error   *string           `json:"error,omitempty"`
+	Panic   *string           `json:"panic,omitempty"`
+}
+
+var ctxType = reflect.TypeOf((*context.Context)(nil)).Elem()
+var errType = reflect.TypeOf((*error)(nil)).Elem()
+
+// UseCassette records or replays calls to `fn` depending
+// on env XGO_MOCK_CASSETTE_MODE, see Record and Replay.
+// With passthrough mode, `fn` is not affected.
+func UseCassette(fn interface{}, file string) func() {
+	mode := os.Getenv(XGO_MOCK_CASSETTE_MODE)
+	switch mode {
+	case CassetteMode_Record:
+		return Record(fn, file)
+	case "", CassetteMode_Replay:
+		return Replay(fn, file)
+	case CassetteMode_Passthrough:
+		return func() {}
+	default:
+		panic(fmt.Errorf("unrecognized %s: %s", XGO_MOCK_CASSETTE_MODE, mode))
+	}
+}
+
+// Record records real calls to `fn`, args and results are
+// serialized with trace.MarshalAnyJSON.
+// The recordings are written to `file` when the returned
+// function is called, recordings of other functions in
+// the same file are kept. A call that panicked is recorded
+// with the panic message, and panics again when replayed.
+func Record(fn interface{}, file string) func() {
+	if file == "" {
+		panic("cassette file cannot be empty")
+	}
+	spy := Spy(fn)
+	_, fnInfo, _, _ := getFunc(fn)
+	funcName := cassetteFuncName(fnInfo)
+
+	return func() {
+		spy.Cancel()
+
+		var calls []*cassetteCall
+		for _, call := range spy.Calls() {
+			cc, err := newCassetteCall(funcName, spy.fnType, call)
+			if err != nil {
+				panic(fmt.Errorf("record %s: %w", funcName, err))
+			}
+			calls = append(calls, cc)
+		}
+		err := writeCassette(file, funcName, calls)
+		if err != nil {
+			panic(fmt.Errorf("record %s: %w", funcName, err))
+		}
+	}
+}
+
+// Replay mocks `fn` with recordings in `file`, calls are
+// matched by arguments excluding context.Context.
+// If there are multiple recordings of the same arguments,
+// they are replayed in order, the last one is repeated.
+// A call without matching recording panics.
+func Replay(fn interface{}, file string) func() {
+	if file == "" {
+		panic("cassette file cannot be empty")
+	}
+	fnType := reflect.TypeOf(fn)
+	if fnType.Kind() != reflect.Func {
+		panic(fmt.Errorf("fn should be func, actual: %T", fn))
+	}
+	recvPtr, fnInfo, funcPC, trappingPC := getFunc(fn)
+	funcName := cassetteFuncName(fnInfo)
+
+	c, err := readCassette(file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			panic(fmt.Errorf("cassette %s not found, record it with %s=%s", file, XGO_MOCK_CASSETTE_MODE, CassetteMode_Record))
+		}
+		panic(err)
+	}
+	recordings := make(map[string][]*cassetteCall)
+	for _, call := range c.Calls {
+		if call.Func != funcName {
+			continue
+		}
+		var buf bytes.Buffer
+		err := json.Compact(&buf, call.Args)
+		if err != nil {
+			panic(fmt.Errorf("cassette %s: %w", file, err))
+		}
+		key := buf.String()
+		recordings[key] = append(recordings[key], call)
+	}
+
+	var mutex sync.Mutex
+	replayed := make(map[string]int)
+	return mock(recvPtr, fnInfo, funcPC, trappingPC, func(ctx context.Context, f *core.FuncInfo, args, results core.Object) error {
+		callArgs := assembleCallArgs(ctx, f, recvPtr, args, fnType.NumIn())
+		argValues := make([]interface{}, len(callArgs))
+		for i, arg := range callArgs {
+			argValues[i] = arg.Interface()
+		}
+		key, err := marshalCassetteArgs(fnType, argValues)
+		if err != nil {
+			panic(fmt.Errorf("replay %s: %w", funcName, err))
+		}
+
+		mutex.Lock()
+		calls := recordings[key]
+		idx := replayed[key]
+		if idx < len(calls)-1 {
+			replayed[key] = idx + 1
+		}
+		mutex.Unlock()
+		if len(calls) == 0 {
+			panic(fmt.Errorf("replay %s: no recording with args %s in cassette %s", funcName, key, file))
+		}
+
+		if calls[idx].Panic != nil {
+			panic(*calls[idx].Panic)
+		}
+		res, err := decodeCassetteResults(fnType, calls[idx])
+		if err != nil {
+			panic(fmt.Errorf("replay %s: %w", funcName, err))
+		}
+		setCallResults(f, results, res)
+		return nil
+	})
+}
+
+func cassetteFuncName(fnInfo *core.FuncInfo) string {
+	return fnInfo.Pkg + "." + fnInfo.IdentityName
+}
+
+// marshalCassetteArgs excludes context.Context
+func marshalCassetteArgs(fnType reflect.Type, args []interface{}) (string, error) {
+	keyArgs := make([]interface{}, 0, len(args))
+	for i, arg := range args {
+		if fnType.In(i).Implements(ctxType) {
+			continue
+		}
+		keyArgs = append(keyArgs, arg)
+	}
+	data, err := trace.MarshalAnyJSON(keyArgs)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func newCassetteCall(funcName string, fnType reflect.Type, call *Call) (*cassetteCall, error) {
+	args, err := marshalCassetteArgs(fnType, call.Args)
+	if err != nil {
+		return nil, err
+	}
+	cc := &cassetteCall{
+		Func: funcName,
+		Args: json.RawMessage(args),
+	}
+	if call.Panic != nil {
+		panicMsg := fmt.Sprint(call.Panic)
+		cc.Panic = &panicMsg
+		return cc, nil
+	}
+	nOut := fnType.NumOut()
+	for i, res := range call.Results {
+		if i == nOut-1 && fnType.Out(i) == errType {
+			if call.Err != nil {
+				errMsg := call.Err.Error()
+				cc.Error = &errMsg
+			}
+			continue
+		}
+		data, err := trace.MarshalAnyJSON(res)
+		if err != nil {
+			return nil, err
+		}
+		cc.Results = append(cc.Results, json.RawMessage(data))
+	}
+	return cc, nil
+}
+
+func decodeCassetteResults(fnType reflect.Type, call *cassetteCall) ([]reflect.Value, error) {
+	nOut := fnType.NumOut()
+	res := make([]reflect.Value, nOut)
+	j := 0
+	for i := 0; i < nOut; i++ {
+		outType := fnType.Out(i)
+		if i == nOut-1 && outType == errType {
+			errVal := reflect.New(errType).Elem()
+			if call.Error != nil {
+				errVal.Set(reflect.ValueOf(errors.New(*call.Error)))
+			}
+			res[i] = errVal
+			continue
+		}
+		if j >= len(call.Results) {
+			return nil, fmt.Errorf("recording has %d results, expect %d", len(call.Results), nOut)
+		}
+		v := reflect.New(outType)
+		err := json.Unmarshal(call.Results[j], v.Interface())
+		if err != nil {
+			return nil, fmt.Errorf("result %d: %w", i, err)
+		}
+		res[i] = v.Elem()
+		j++
+	}
+	return res, nil
+}
+
+func readCassette(file string) (*cassette, error) {
+	var data []byte
+	var err error
+	trap.Direct(func() {
+		data, err = ioutil.ReadFile(file)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var c cassette
+	err = json.Unmarshal(data, &c)
+	if err != nil {
+		return nil, fmt.Errorf("cassette %s: %w", file, err)
+	}
+	return &c, nil
+}
+
+// writeCassette replaces recordings of `funcName` in `file`
+func writeCassette(file string, funcName string, calls []*cassetteCall) error {
+	c, err := readCassette(file)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		c = &cassette{}
+	}
+	newCalls := make([]*cassetteCall, 0, len(c.Calls)+len(calls))
+	for _, call := range c.Calls {
+		if call.Func == funcName {
+			continue
+		}
+		newCalls = append(newCalls, call)
+	}
+	c.Calls = append(newCalls, calls...)
+
+	data, err := json.MarshalIndent(c, "", "    ")
+	if err != nil {
+		return err
+	}
+	trap.Direct(func() {
+		err = os.MkdirAll(filepath.Dir(file), 0755)
+		if err != nil {
+			return
+		}
+		err = ioutil.WriteFile(file, data, 0644)
+	})
+	return err
+}
@@ -0,0 +1,435 @@
+// Package clock provides a deterministic fake clock
+// by mocking functions of the time package.
+//
+// Example:
+//
+//	c := clock.Install(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+//	defer c.Cancel()
+//
+//	go func() {
+//		time.Sleep(time.Minute)
+//		close(done)
+//	}()
+//	c.BlockUntil(1)
+//	c.Advance(time.Minute)
+//	<-done
+package clock
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/xhd2015/xgo/runtime/core"
+	"github.com/xhd2015/xgo/runtime/mock"
+	"github.com/xhd2015/xgo/runtime/trap"
+)
+
+// Clock is a fake clock, time only moves when
+// Advance or Set is called.
+// The following functions are mocked:
+//   - time.Now, time.Since
+//   - time.Sleep
+//   - time.After, time.NewTimer, time.AfterFunc
+//   - time.NewTicker
+//   - (*time.Timer).Stop, (*time.Timer).Reset
+//   - (*time.Ticker).Stop, (*time.Ticker).Reset
+type Clock struct {
+	cancels []func()
+
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*waiter
+	timers  map[*time.Timer]*waiter
+	tickers map[*time.Ticker]*waiter
+}
+
+// waiter is a sleeper, timer or ticker waiting
+// for the clock to reach its deadline
+type waiter struct {
+	deadline time.Time
+	period   time.Duration // ticker only
+	sleep    bool
+	active   bool
+	fire     func(now time.Time)
+}
+
+// Install installs a fake clock starting at `start`,
+// if `start` is zero, the real current time is used.
+// The scope is the same as mock.Mock: after init, only
+// current goroutine and goroutines created by it after
+// Install are affected.
+func Install(start time.Time) *Clock {
+	if start.IsZero() {
+		trap.Direct(func() {
+			start = time.Now()
+		})
+	}
+	c := &Clock{
+		now:     start,
+		timers:  make(map[*time.Timer]*waiter),
+		tickers: make(map[*time.Ticker]*waiter),
+	}
+	c.cond = sync.NewCond(&c.mutex)
+
+	c.cancels = []func(){
+		mock.Mock(time.Now, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+			results.GetFieldIndex(0).Set(c.Now())
+			return nil
+		}),
+		mock.Mock(time.Since, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+			t := args.GetFieldIndex(0).Value().(time.Time)
+			results.GetFieldIndex(0).Set(c.Now().Sub(t))
+			return nil
+		}),
+		mock.Mock(time.Sleep, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+			c.sleep(args.GetFieldIndex(0).Value().(time.Duration))
+			return nil
+		}),
+		mock.Mock(time.After, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+			timer := c.newTimer(args.GetFieldIndex(0).Value().(time.Duration), nil)
+			results.GetFieldIndex(0).Set(timer.C)
+			return nil
+		}),
+		mock.Mock(time.NewTimer, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+			results.GetFieldIndex(0).Set(c.newTimer(args.GetFieldIndex(0).Value().(time.Duration), nil))
+			return nil
+		}),
+		mock.Mock(time.AfterFunc, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+			d := args.GetFieldIndex(0).Value().(time.Duration)
+			f := args.GetFieldIndex(1).Value().(func())
+			results.GetFieldIndex(0).Set(c.newTimer(d, f))
+			return nil
+		}),
+		mock.Mock(time.NewTicker, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+			results.GetFieldIndex(0).Set(c.newTicker(args.GetFieldIndex(0).Value().(time.Duration)))
+			return nil
+		}),
+		mock.Mock((*time.Timer).Stop, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+			active, ok := c.stopTimer(args.GetFieldIndex(0).Value().(*time.Timer))
+			if !ok {
+				return mock.ErrCallOld
+			}
+			results.GetFieldIndex(0).Set(active)
+			return nil
+		}),
+		mock.Mock((*time.Timer).Reset, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+			active, ok := c.resetTimer(args.GetFieldIndex(0).Value().(*time.Timer), args.GetFieldIndex(1).Value().(time.Duration))
+			if !ok {
+				return mock.ErrCallOld
+			}
+			results.GetFieldIndex(0).Set(active)
+			return nil
+		}),
+		mock.Mock((*time.Ticker).Stop, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+			if !c.stopTicker(args.GetFieldIndex(0).Value().(*time.Ticker)) {
+				return mock.ErrCallOld
+			}
+			return nil
+		}),
+		mock.Mock((*time.Ticker).Reset, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+			if !c.resetTicker(args.GetFieldIndex(0).Value().(*time.Ticker), args.GetFieldIndex(1).Value().(time.Duration)) {
+				return mock.ErrCallOld
+			}
+			return nil
+		}),
+	}
+	return c
+}
+
+// Now returns current time of the clock
+func (c *Clock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d, sleepers,
+// timers and tickers are fired in deadline order.
+func (c *Clock) Advance(d time.Duration) {
+	if d < 0 {
+		panic("clock: cannot advance by negative duration")
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.advanceTo(c.now.Add(d))
+}
+
+// Set sets the clock to t, if t is after current
+// time, it is the same as Advance. Otherwise the
+// clock goes back without firing anything.
+func (c *Clock) Set(t time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if !t.After(c.now) {
+		c.now = t
+		return
+	}
+	c.advanceTo(t)
+}
+
+// Sleepers returns number of goroutines
+// blocked in time.Sleep
+func (c *Clock) Sleepers() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.countSleepers()
+}
+
+// BlockUntil blocks until at least n goroutines
+// are blocked in time.Sleep, it is typically
+// called before Advance to avoid races with
+// the goroutines under test.
+func (c *Clock) BlockUntil(n int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for c.countSleepers() < n {
+		c.cond.Wait()
+	}
+}
+
+// Pending returns number of sleepers, timers and
+// tickers waiting for the clock
+func (c *Clock) Pending() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.waiters)
+}
+
+// Cancel removes the mocks, goroutines still
+// blocked in time.Sleep are woken up.
+func (c *Clock) Cancel() {
+	for _, cancel := range c.cancels {
+		cancel()
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	waiters := c.waiters
+	c.waiters = nil
+	for _, w := range waiters {
+		w.active = false
+		if w.sleep {
+			w.fire(c.now)
+		}
+	}
+}
+
+func (c *Clock) sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	done := make(chan struct{})
+	c.mutex.Lock()
+	c.addWaiter(&waiter{
+		deadline: c.now.Add(d),
+		sleep:    true,
+		fire: func(now time.Time) {
+			close(done)
+		},
+	})
+	c.cond.Broadcast()
+	c.mutex.Unlock()
+	<-done
+}
+
+// newStoppedTimer creates a real timer which never fires,
+// so calling its methods outside the clock's scope, e.g.
+// from another goroutine or after Cancel, is safe
+func newStoppedTimer(afterFunc bool) (timer *time.Timer) {
+	trap.Direct(func() {
+		if afterFunc {
+			timer = time.AfterFunc(math.MaxInt64, func() {})
+		} else {
+			timer = time.NewTimer(math.MaxInt64)
+		}
+		timer.Stop()
+	})
+	return timer
+}
+
+// newTimer creates a timer, if f is not nil,
+// it is called in its own goroutine when fired
+func (c *Clock) newTimer(d time.Duration, f func()) *time.Timer {
+	timer := newStoppedTimer(f != nil)
+	var fire func(now time.Time)
+	if f != nil {
+		fire = func(now time.Time) {
+			go f()
+		}
+	} else {
+		ch := make(chan time.Time, 1)
+		timer.C = ch
+		fire = func(now time.Time) {
+			select {
+			case ch <- now:
+			default:
+			}
+		}
+	}
+	w := &waiter{fire: fire}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.timers[timer] = w
+	w.deadline = c.now.Add(d)
+	c.addWaiter(w)
+	if d <= 0 {
+		c.advanceTo(c.now)
+	}
+	return timer
+}
+
+func (c *Clock) newTicker(d time.Duration) *time.Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	ch := make(chan time.Time, 1)
+	var ticker *time.Ticker
+	trap.Direct(func() {
+		ticker = time.NewTicker(math.MaxInt64)
+		ticker.Stop()
+	})
+	ticker.C = ch
+	w := &waiter{
+		period: d,
+		fire: func(now time.Time) {
+			// drop ticks for slow receivers
+			select {
+			case ch <- now:
+			default:
+			}
+		},
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.tickers[ticker] = w
+	w.deadline = c.now.Add(d)
+	c.addWaiter(w)
+	return ticker
+}
+
+// stopTimer returns ok=false if timer is not
+// created by the clock
+func (c *Clock) stopTimer(timer *time.Timer) (active bool, ok bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	w := c.timers[timer]
+	if w == nil {
+		return false, false
+	}
+	active = w.active
+	c.removeWaiter(w)
+	return active, true
+}
+
+func (c *Clock) resetTimer(timer *time.Timer, d time.Duration) (active bool, ok bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	w := c.timers[timer]
+	if w == nil {
+		return false, false
+	}
+	active = w.active
+	c.removeWaiter(w)
+	w.deadline = c.now.Add(d)
+	c.addWaiter(w)
+	if d <= 0 {
+		c.advanceTo(c.now)
+	}
+	return active, true
+}
+
+func (c *Clock) stopTicker(ticker *time.Ticker) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	w := c.tickers[ticker]
+	if w == nil {
+		return false
+	}
+	c.removeWaiter(w)
+	return true
+}
+
+func (c *Clock) resetTicker(ticker *time.Ticker, d time.Duration) bool {
+	if d <= 0 {
+		panic("non-positive interval for Ticker.Reset")
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	w := c.tickers[ticker]
+	if w == nil {
+		return false
+	}
+	c.removeWaiter(w)
+	w.period = d
+	w.deadline = c.now.Add(d)
+	c.addWaiter(w)
+	return true
+}
+
+// advanceTo must be called with mutex held
+func (c *Clock) advanceTo(t time.Time) {
+	for {
+		w := c.nextDue(t)
+		if w == nil {
+			break
+		}
+		if w.deadline.After(c.now) {
+			c.now = w.deadline
+		}
+		if w.period > 0 {
+			w.deadline = w.deadline.Add(w.period)
+		} else {
+			c.removeWaiter(w)
+		}
+		w.fire(c.now)
+	}
+	c.now = t
+}
+
+// nextDue returns the earliest waiter whose
+// deadline is not after t, waiters with the
+// same deadline are fired in scheduling order
+func (c *Clock) nextDue(t time.Time) *waiter {
+	var due *waiter
+	for _, w := range c.waiters {
+		if w.deadline.After(t) {
+			continue
+		}
+		if due == nil || w.deadline.Before(due.deadline) {
+			due = w
+		}
+	}
+	return due
+}
+
+func (c *Clock) addWaiter(w *waiter) {
+	w.active = true
+	c.waiters = append(c.waiters, w)
+}
+
+func (c *Clock) removeWaiter(w *waiter) {
+	if !w.active {
+		return
+	}
+	w.active = false
+	for i, x := range c.waiters {
+		if x == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+func (c *Clock) countSleepers() int {
+	n := 0
+	for _, w := range c.waiters {
+		if w.sleep {
+			n++
+		}
+	}
+	return n
+}
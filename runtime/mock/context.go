@@ -0,0 +1,48 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xhd2015/xgo/runtime/trap"
+)
+
+// WithContext returns a context carrying a mock on `fn`,
+// whose first argument must be context.Context.
+// The mock applies to calls of `fn` whose ctx is, or
+// derives from, the returned context, regardless of
+// which goroutine makes the call. So unlike Mock, it
+// works with worker pools and servers under test.
+// With caller-aware options like FromCaller, calls are
+// tracked until ctx is done, so a cancellable ctx is
+// preferred.
+//
+// Example:
+//
+//	ctx = mock.WithContext(ctx, repo.GetUser, interceptor)
+//	resp := server.Handle(ctx, req)
+func WithContext(ctx context.Context, fn interface{}, interceptor Interceptor, opts ...Option) context.Context {
+	if ctx == nil {
+		panic("ctx cannot be nil")
+	}
+	recvPtr, fnInfo, funcPC, trappingPC := getFunc(fn)
+	if !fnInfo.FirstArgCtx {
+		panic(fmt.Errorf("failed to setup mock for: %s, first argument should be context.Context", fnInfo.DisplayName()))
+	}
+	o := parseOptions(opts)
+	if o.t != nil {
+		panic("ForTest cannot be used with WithContext")
+	}
+	if len(o.conds) > 0 {
+		untrack := trap.TrackFrames()
+		if done := ctx.Done(); done != nil {
+			go func() {
+				<-done
+				untrack()
+			}()
+		}
+		// else the context never ends, neither does tracking
+	}
+	recvPtr, interceptor = o.wrap(recvPtr, interceptor)
+	return trap.WithContextInterceptor(ctx, fnInfo, newMockInterceptor(recvPtr, fnInfo, funcPC, trappingPC, interceptor))
+}
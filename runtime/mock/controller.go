@@ -0,0 +1,302 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/core"
+)
+
+// Controller tracks expectations of mocks
+// created through it, and verifies them when
+// the test finishes.
+//
+// Example:
+//
+//	ctrl := mock.NewController(t)
+//	ctrl.Mock(fetch, interceptor).Times(2)
+type Controller struct {
+	t testing.TB
+
+	mutex        sync.Mutex
+	expectations []*Expectation
+	callCount    map[*core.FuncInfo]int
+	finished     bool
+}
+
+// Expectation describes how many times a
+// mocked function is expected to be called.
+// By default a mocked function should be
+// called at least once.
+type Expectation struct {
+	ctrl   *Controller
+	fnInfo *core.FuncInfo
+	cancel func()
+	g      uintptr // goroutine that created the mock
+
+	min int
+	max int // -1: no upper limit
+
+	count     int
+	cancelled bool
+	prereqs   []*Expectation // expectations called before this one
+	nexts     []*Expectation // expectations called after this one
+}
+
+// NewController creates a controller bound to t,
+// expectations are verified via t.Cleanup.
+func NewController(t testing.TB) *Controller {
+	if t == nil {
+		panic("t cannot be nil")
+	}
+	ctrl := &Controller{
+		t:         t,
+		callCount: make(map[*core.FuncInfo]int),
+	}
+	t.Cleanup(ctrl.Finish)
+	return ctrl
+}
+
+// Mock is like Mock(fn, interceptor), but records calls
+// of `fn`. If `interceptor` is nil, the original function
+// is called.
+func (c *Controller) Mock(fn interface{}, interceptor Interceptor) *Expectation {
+	recvPtr, fnInfo, funcPC, trappingPC := getFunc(fn)
+	return c.expect(recvPtr, fnInfo, funcPC, trappingPC, interceptor)
+}
+
+func (c *Controller) MockByName(pkgPath string, funcName string, interceptor Interceptor) *Expectation {
+	recvPtr, fnInfo, funcPC, trappingPC := getFuncByName(pkgPath, funcName)
+	return c.expect(recvPtr, fnInfo, funcPC, trappingPC, interceptor)
+}
+
+func (c *Controller) MockMethodByName(instance interface{}, method string, interceptor Interceptor) *Expectation {
+	recvPtr, fnInfo, funcPC, trappingPC := getMethodByName(instance, method)
+	return c.expect(recvPtr, fnInfo, funcPC, trappingPC, interceptor)
+}
+
+// InOrder declares that each expectation can only
+// be called after its previous ones have been satisfied,
+// and no longer be called once a later one is called.
+func (c *Controller) InOrder(expectations ...*Expectation) {
+	for i := 1; i < len(expectations); i++ {
+		cur := expectations[i]
+		if cur.ctrl != c {
+			panic(fmt.Errorf("expectation of %s belongs to another controller", cur.fnInfo.DisplayName()))
+		}
+		prev := expectations[i-1]
+		cur.prereqs = append(cur.prereqs, prev)
+		prev.nexts = append(prev.nexts, cur)
+	}
+}
+
+// CallCount returns how many times calls to `fn` have
+// been intercepted by this controller.
+func (c *Controller) CallCount(fn interface{}) int {
+	_, fnInfo, _, _ := getFunc(fn)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.callCount[fnInfo]
+}
+
+// Finish verifies all expectations and cancels their
+// mocks, it is automatically called when the test finishes.
+func (c *Controller) Finish() {
+	c.t.Helper()
+	c.mutex.Lock()
+	if c.finished {
+		c.mutex.Unlock()
+		return
+	}
+	c.finished = true
+	expectations := c.expectations
+	c.mutex.Unlock()
+
+	for _, e := range expectations {
+		count := e.Count()
+		if count < e.min {
+			if count == 0 {
+				c.t.Errorf("mock: %s expected to be called %s, actual: never called", e.fnInfo.DisplayName(), e.describe())
+			} else {
+				c.t.Errorf("mock: %s expected to be called %s, actual: %d", e.fnInfo.DisplayName(), e.describe(), count)
+			}
+		}
+	}
+	for _, e := range expectations {
+		e.Cancel()
+	}
+}
+
+func (c *Controller) expect(recvPtr interface{}, fnInfo *core.FuncInfo, funcPC uintptr, trappingPC uintptr, interceptor Interceptor) *Expectation {
+	e := &Expectation{
+		ctrl:   c,
+		fnInfo: fnInfo,
+		min:    1,
+		max:    -1,
+	}
+	e.g = uintptr(__xgo_link_getcurg())
+	e.cancel = mock(recvPtr, fnInfo, funcPC, trappingPC, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+		if !c.onCall(e) {
+			// cancelled
+			return ErrCallOld
+		}
+		if interceptor == nil {
+			return ErrCallOld
+		}
+		return interceptor(ctx, fn, args, results)
+	})
+
+	c.mutex.Lock()
+	c.expectations = append(c.expectations, e)
+	c.mutex.Unlock()
+	return e
+}
+
+// onCall records a call, it returns false if
+// e is cancelled
+func (c *Controller) onCall(e *Expectation) bool {
+	c.t.Helper()
+	c.mutex.Lock()
+	if e.cancelled {
+		c.mutex.Unlock()
+		return false
+	}
+	e.count++
+	count := e.count
+	c.callCount[e.fnInfo]++
+
+	var unsatisfied []string
+	walkOrder(e, func(x *Expectation) []*Expectation { return x.prereqs }, func(prereq *Expectation) {
+		if prereq.count < prereq.min {
+			unsatisfied = append(unsatisfied, prereq.fnInfo.DisplayName())
+		}
+	})
+	var called []string
+	walkOrder(e, func(x *Expectation) []*Expectation { return x.nexts }, func(next *Expectation) {
+		if next.count > 0 {
+			called = append(called, next.fnInfo.DisplayName())
+		}
+	})
+	c.mutex.Unlock()
+
+	if len(unsatisfied) > 0 {
+		c.t.Errorf("mock: %s called out of order, expect %s to be called before", e.fnInfo.DisplayName(), strings.Join(unsatisfied, ","))
+	}
+	if len(called) > 0 {
+		c.t.Errorf("mock: %s called out of order, expect it to be called before %s", e.fnInfo.DisplayName(), strings.Join(called, ","))
+	}
+	if e.max >= 0 && count > e.max {
+		c.t.Errorf("mock: %s expected to be called %s, actual: %d", e.fnInfo.DisplayName(), e.describe(), count)
+	}
+	return true
+}
+
+// Times expects the function to be called exactly n times.
+func (e *Expectation) Times(n int) *Expectation {
+	if n < 0 {
+		panic(fmt.Errorf("times cannot be negative: %d", n))
+	}
+	e.min = n
+	e.max = n
+	return e
+}
+
+// AtLeast expects the function to be called at least n times.
+func (e *Expectation) AtLeast(n int) *Expectation {
+	if n < 0 {
+		panic(fmt.Errorf("times cannot be negative: %d", n))
+	}
+	e.min = n
+	e.max = -1
+	return e
+}
+
+// AtMost expects the function to be called at most n times.
+func (e *Expectation) AtMost(n int) *Expectation {
+	if n < 0 {
+		panic(fmt.Errorf("times cannot be negative: %d", n))
+	}
+	e.min = 0
+	e.max = n
+	return e
+}
+
+// AnyTimes allows the function to be called any times, including zero.
+func (e *Expectation) AnyTimes() *Expectation {
+	e.min = 0
+	e.max = -1
+	return e
+}
+
+// Never expects the function not to be called.
+func (e *Expectation) Never() *Expectation {
+	return e.Times(0)
+}
+
+// Count returns how many times the function has been called.
+func (e *Expectation) Count() int {
+	e.ctrl.mutex.Lock()
+	defer e.ctrl.mutex.Unlock()
+	return e.count
+}
+
+// Cancel disables the underlying mock, the expectation
+// is still verified when the test finishes. It can be
+// called from any goroutine, the mock is removed when
+// called from the goroutine that created it, or else
+// when that goroutine exits.
+func (e *Expectation) Cancel() {
+	e.ctrl.mutex.Lock()
+	if e.cancelled {
+		e.ctrl.mutex.Unlock()
+		return
+	}
+	e.cancelled = true
+	e.ctrl.mutex.Unlock()
+	if uintptr(__xgo_link_getcurg()) == e.g {
+		e.cancel()
+	}
+}
+
+// walkOrder visits expectations transitively reachable
+// from e by `edges`, excluding e itself
+func walkOrder(e *Expectation, edges func(x *Expectation) []*Expectation, visit func(x *Expectation)) {
+	seen := map[*Expectation]bool{e: true}
+	queue := edges(e)
+	for len(queue) > 0 {
+		x := queue[0]
+		queue = queue[1:]
+		if seen[x] {
+			continue
+		}
+		seen[x] = true
+		visit(x)
+		queue = append(queue, edges(x)...)
+	}
+}
+
+func (e *Expectation) describe() string {
+	if e.max < 0 {
+		return "at least " + formatTimes(e.min)
+	}
+	if e.min == e.max {
+		if e.min == 0 {
+			return "never"
+		}
+		return "exactly " + formatTimes(e.min)
+	}
+	if e.min == 0 {
+		return "at most " + formatTimes(e.max)
+	}
+	return fmt.Sprintf("between %d and %d times", e.min, e.max)
+}
+
+func formatTimes(n int) string {
+	if n == 1 {
+		return "1 time"
+	}
+	return fmt.Sprintf("%d times", n)
+}
@@ -0,0 +1,78 @@
+package mock
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/xhd2015/xgo/runtime/core"
+	"github.com/xhd2015/xgo/runtime/trap"
+)
+
+// Setenv overrides environment variable `key` seen by
+// os.Getenv, os.LookupEnv and os.Environ, the real
+// environment is not changed.
+// The scope is the same as Mock, so unlike t.Setenv,
+// it can be used with t.Parallel.
+// Later Setenv and Unsetenv override earlier ones.
+// The returned function can be used to cancel it.
+func Setenv(key string, value string) func() {
+	return overrideEnv(key, value, true)
+}
+
+// Unsetenv hides environment variable `key` from
+// os.Getenv, os.LookupEnv and os.Environ, see Setenv.
+func Unsetenv(key string) func() {
+	return overrideEnv(key, "", false)
+}
+
+func overrideEnv(key string, value string, set bool) func() {
+	if key == "" {
+		panic("key cannot be empty")
+	}
+	// later interceptors run first, so the latest override wins
+	cancelGetenv := trap.AddFuncInterceptor(os.Getenv, &trap.Interceptor{
+		Pre: func(ctx context.Context, f *core.FuncInfo, args, result core.Object) (interface{}, error) {
+			if args.GetFieldIndex(0).Value().(string) != key {
+				return nil, nil
+			}
+			result.GetFieldIndex(0).Set(value)
+			return nil, trap.ErrAbort
+		},
+	})
+	cancelLookupEnv := trap.AddFuncInterceptor(os.LookupEnv, &trap.Interceptor{
+		Pre: func(ctx context.Context, f *core.FuncInfo, args, result core.Object) (interface{}, error) {
+			if args.GetFieldIndex(0).Value().(string) != key {
+				return nil, nil
+			}
+			result.GetFieldIndex(0).Set(value)
+			result.GetFieldIndex(1).Set(set)
+			return nil, trap.ErrAbort
+		},
+	})
+	// post interceptors run in the order they are added,
+	// so overrides are applied from the earliest to the latest
+	cancelEnviron := trap.AddFuncInterceptor(os.Environ, &trap.Interceptor{
+		Post: func(ctx context.Context, f *core.FuncInfo, args, result core.Object, data interface{}) error {
+			env, _ := result.GetFieldIndex(0).Value().([]string)
+			newEnv := make([]string, 0, len(env)+1)
+			prefix := key + "="
+			for _, kv := range env {
+				if strings.HasPrefix(kv, prefix) {
+					continue
+				}
+				newEnv = append(newEnv, kv)
+			}
+			if set {
+				newEnv = append(newEnv, prefix+value)
+			}
+			result.GetFieldIndex(0).Set(newEnv)
+			return nil
+		},
+	})
+	return func() {
+		cancelEnviron()
+		cancelLookupEnv()
+		cancelGetenv()
+	}
+}
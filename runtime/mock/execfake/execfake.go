@@ -0,0 +1,290 @@
+// Package execfake fakes commands started via os/exec
+// with scripted stdout, stderr, exit code and delay.
+//
+// A matched command is started as a real process by
+// re-executing the test binary, which writes the scripted
+// output and exits in init. So Run, Output, CombinedOutput,
+// Start, Wait, Process and ProcessState all behave the same
+// as a real command.
+//
+// Example:
+//
+//	fake := execfake.Install(t)
+//	fake.Expect("git", "rev-parse", "HEAD").Stdout("abc\n")
+//	fake.Expect("kubectl", "apply", mock.Any()).Stderr("denied\n").ExitCode(1)
+//
+//	// code under test...
+package execfake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xhd2015/xgo/runtime/core"
+	"github.com/xhd2015/xgo/runtime/mock"
+	"github.com/xhd2015/xgo/runtime/trap"
+)
+
+// XGO_EXECFAKE_SCRIPT is set on the re-executed
+// test binary, pointing to the script file
+const XGO_EXECFAKE_SCRIPT = "XGO_EXECFAKE_SCRIPT"
+
+func init() {
+	file := os.Getenv(XGO_EXECFAKE_SCRIPT)
+	if file == "" {
+		return
+	}
+	os.Exit(runScript(file))
+}
+
+// Fake holds expected commands, starting a command
+// not expected fails the test.
+type Fake struct {
+	t       testing.TB
+	exe     string
+	dir     string
+	cancels []func()
+
+	mutex    sync.Mutex
+	commands []*Command
+	calls    [][]string
+}
+
+// Command is an expected command line with
+// scripted behavior
+type Command struct {
+	matchers []mock.Matcher
+	script   script
+
+	scriptFile string
+}
+
+type script struct {
+	Stdout   string        `json:"stdout"`
+	Stderr   string        `json:"stderr"`
+	ExitCode int           `json:"exit_code"`
+	Delay    time.Duration `json:"delay"`
+}
+
+// Install fakes commands started via os/exec, the
+// scope is the same as mock.Mock.
+func Install(t testing.TB) *Fake {
+	if t == nil {
+		panic("t cannot be nil")
+	}
+	var exe string
+	var dir string
+	var err error
+	trap.Direct(func() {
+		exe, err = os.Executable()
+		if err != nil {
+			return
+		}
+		dir, err = ioutil.TempDir("", "xgo-execfake")
+	})
+	if err != nil {
+		t.Fatalf("execfake: %v", err)
+	}
+	c := &Fake{
+		t:   t,
+		exe: exe,
+		dir: dir,
+	}
+	c.cancels = []func(){
+		// bypass path lookup, the command may not exist
+		mock.Mock(exec.Command, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+			name := args.GetFieldIndex(0).Value().(string)
+			arg := args.GetFieldIndex(1).Value().([]string)
+			results.GetFieldIndex(0).Set(&exec.Cmd{
+				Path: name,
+				Args: append([]string{name}, arg...),
+			})
+			return nil
+		}),
+		mock.Mock((*exec.Cmd).Start, c.start),
+	}
+	t.Cleanup(c.Cancel)
+	return c
+}
+
+// Expect adds an expected command line, `argv` includes the
+// command name, each one can be a string or a mock.Matcher.
+// Commands are matched in the order they are added.
+func (c *Fake) Expect(argv ...interface{}) *Command {
+	if len(argv) == 0 {
+		panic("argv cannot be empty")
+	}
+	matchers := make([]mock.Matcher, len(argv))
+	for i, arg := range argv {
+		switch arg := arg.(type) {
+		case mock.Matcher:
+			matchers[i] = arg
+		case string:
+			matchers[i] = mock.Eq(arg)
+		default:
+			panic(fmt.Errorf("argv should be string or mock.Matcher, actual: %T", arg))
+		}
+	}
+	cmd := &Command{matchers: matchers}
+	c.mutex.Lock()
+	c.commands = append(c.commands, cmd)
+	c.mutex.Unlock()
+	return cmd
+}
+
+// Calls returns argv of all started commands in
+// order, including unexpected ones
+func (c *Fake) Calls() [][]string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	calls := make([][]string, len(c.calls))
+	copy(calls, c.calls)
+	return calls
+}
+
+// Cancel removes the mocks, it is automatically
+// called when the test finishes.
+func (c *Fake) Cancel() {
+	for _, cancel := range c.cancels {
+		cancel()
+	}
+	c.cancels = nil
+	trap.Direct(func() {
+		os.RemoveAll(c.dir)
+	})
+}
+
+// Stdout sets output written to stdout
+func (c *Command) Stdout(s string) *Command {
+	c.script.Stdout = s
+	return c
+}
+
+// Stderr sets output written to stderr
+func (c *Command) Stderr(s string) *Command {
+	c.script.Stderr = s
+	return c
+}
+
+// ExitCode sets exit code of the command, a non-zero
+// exit code results in *exec.ExitError
+func (c *Command) ExitCode(code int) *Command {
+	c.script.ExitCode = code
+	return c
+}
+
+// Delay makes the command wait d before writing output
+func (c *Command) Delay(d time.Duration) *Command {
+	c.script.Delay = d
+	return c
+}
+
+func (c *Command) match(argv []string) bool {
+	if len(argv) != len(c.matchers) {
+		return false
+	}
+	for i, m := range c.matchers {
+		if !m.Match(argv[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Fake) start(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+	cmd := args.GetFieldIndex(0).Value().(*exec.Cmd)
+	argv := cmd.Args
+	if len(argv) == 0 {
+		argv = []string{cmd.Path}
+	}
+
+	c.mutex.Lock()
+	c.calls = append(c.calls, argv)
+	var expected *Command
+	for _, command := range c.commands {
+		if command.match(argv) {
+			expected = command
+			break
+		}
+	}
+	var scriptFile string
+	var err error
+	if expected != nil {
+		scriptFile, err = c.writeScript(expected)
+	}
+	c.mutex.Unlock()
+
+	if expected == nil {
+		c.t.Errorf("execfake: unexpected command: %q", argv)
+		results.(core.ObjectWithErr).GetErr().Set(&exec.Error{Name: cmd.Path, Err: fmt.Errorf("execfake: unexpected command")})
+		return nil
+	}
+	if err != nil {
+		results.(core.ObjectWithErr).GetErr().Set(err)
+		return nil
+	}
+
+	// start the test binary instead, and restore
+	// the command after started
+	path, env := cmd.Path, cmd.Env
+	trap.Direct(func() {
+		if env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env[:len(cmd.Env):len(cmd.Env)], XGO_EXECFAKE_SCRIPT+"="+scriptFile)
+		cmd.Path = c.exe
+		err = cmd.Start()
+	})
+	cmd.Path, cmd.Env = path, env
+	results.(core.ObjectWithErr).GetErr().Set(err)
+	return nil
+}
+
+// writeScript must be called with mutex held
+func (c *Fake) writeScript(cmd *Command) (string, error) {
+	if cmd.scriptFile != "" {
+		return cmd.scriptFile, nil
+	}
+	data, err := json.Marshal(cmd.script)
+	if err != nil {
+		return "", err
+	}
+	file := filepath.Join(c.dir, fmt.Sprintf("script_%d.json", len(c.calls)))
+	trap.Direct(func() {
+		err = ioutil.WriteFile(file, data, 0644)
+	})
+	if err != nil {
+		return "", err
+	}
+	cmd.scriptFile = file
+	return file, nil
+}
+
+// runScript runs in the re-executed test binary
+func runScript(file string) int {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "execfake: %v\n", err)
+		return 1
+	}
+	var s script
+	err = json.Unmarshal(data, &s)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "execfake: %v\n", err)
+		return 1
+	}
+	if s.Delay > 0 {
+		time.Sleep(s.Delay)
+	}
+	os.Stdout.WriteString(s.Stdout)
+	os.Stderr.WriteString(s.Stderr)
+	return s.ExitCode
+}
@@ -0,0 +1,577 @@
+// Package fsfake redirects common file operations of the os
+// package to an overlay rooted at a temp dir, so writes through
+// them do not reach the real disk. See FS for the exact list of
+// overlaid functions, all others, e.g. os.Rename, os.Chmod and
+// os.Symlink, still operate on the real disk.
+//
+// Reads fall through to the real disk unless the file has been
+// written or removed through the overlay. Writes copy the file
+// up into the overlay first. Removals of real files are recorded
+// and hide them from later reads.
+//
+// Example:
+//
+//	fs := fsfake.Install(t)
+//	fs.Seed("testdata/config", "/etc/app")
+//
+//	// code under test...
+//
+//	for _, change := range fs.Changes() {
+//		t.Logf("%s %s", change.Kind, change.Path)
+//	}
+package fsfake
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/mock"
+	"github.com/xhd2015/xgo/runtime/trap"
+)
+
+type ChangeKind string
+
+const (
+	ChangeKind_Added    ChangeKind = "added"
+	ChangeKind_Modified ChangeKind = "modified"
+	ChangeKind_Removed  ChangeKind = "removed"
+)
+
+// Change is a file changed through the overlay
+type Change struct {
+	// Path is the absolute path seen by the code under test
+	Path string
+	Kind ChangeKind
+}
+
+// FS is a filesystem overlay, the following
+// functions are mocked:
+//   - os.OpenFile, os.ReadFile, os.WriteFile
+//   - os.Stat, os.Lstat, os.ReadDir
+//   - os.Mkdir, os.MkdirAll, os.Remove, os.RemoveAll
+//   - ioutil.ReadFile, ioutil.ReadDir
+//
+// NOTE: (*os.File).Name() of a file opened from the
+// overlay returns the path inside the overlay root.
+type FS struct {
+	t       testing.TB
+	root    string
+	cancels []func()
+
+	mutex   sync.Mutex
+	deleted map[string]bool
+
+	// state after last Seed, used by Changes
+	baseline        map[string][]byte
+	baselineDeleted map[string]bool
+}
+
+// Install creates an overlay in a temp dir and redirects
+// file operations to it. The scope is the same as mock.Mock.
+// The overlay is removed when the test finishes.
+func Install(t testing.TB) *FS {
+	if t == nil {
+		panic("t cannot be nil")
+	}
+	var root string
+	var err error
+	trap.Direct(func() {
+		root, err = ioutil.TempDir("", "xgo-fsfake")
+	})
+	if err != nil {
+		t.Fatalf("fsfake: %v", err)
+	}
+	c := &FS{
+		t:       t,
+		root:    root,
+		deleted: make(map[string]bool),
+	}
+	c.cancels = append(c.cancels,
+		mock.Patch(os.OpenFile, c.openFile),
+		mock.Patch(os.Stat, c.stat),
+		mock.Patch(os.Lstat, c.lstat),
+		mock.Patch(os.Remove, c.remove),
+		mock.Patch(os.RemoveAll, c.removeAll),
+		mock.Patch(os.Mkdir, c.mkdir),
+		mock.Patch(os.MkdirAll, c.mkdirAll),
+		mock.Patch(ioutil.ReadFile, c.readFile),
+		mock.Patch(ioutil.ReadDir, c.readDirInfo),
+	)
+	c.cancels = append(c.cancels, c.patchGo116()...)
+	c.snapshot()
+	t.Cleanup(c.Cancel)
+	return c
+}
+
+// Root returns the overlay root on the real disk
+func (c *FS) Root() string {
+	return c.root
+}
+
+// Seed copies files under `srcDir` on the real disk
+// to `dstDir` in the overlay, typically from testdata.
+// Changes are reported relative to the seeded state.
+func (c *FS) Seed(srcDir string, dstDir string) {
+	c.t.Helper()
+	var err error
+	trap.Direct(func() {
+		dst := c.abs(dstDir)
+		err = filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(srcDir, path)
+			if err != nil {
+				return err
+			}
+			target := c.upperPath(filepath.Join(dst, rel))
+			if info.IsDir() {
+				return os.MkdirAll(target, 0755)
+			}
+			return copyFile(path, target, info.Mode())
+		})
+	})
+	if err != nil {
+		c.t.Fatalf("fsfake: seed %s: %v", srcDir, err)
+	}
+	c.snapshot()
+}
+
+// Changes returns files added, modified or removed
+// through the overlay, sorted by path
+func (c *FS) Changes() []*Change {
+	c.t.Helper()
+	c.mutex.Lock()
+	baseline := c.baseline
+	baselineDeleted := c.baselineDeleted
+	deleted := make([]string, 0, len(c.deleted))
+	for path := range c.deleted {
+		deleted = append(deleted, path)
+	}
+	c.mutex.Unlock()
+
+	var changes []*Change
+	var err error
+	trap.Direct(func() {
+		var files map[string][]byte
+		files, err = c.upperFiles()
+		if err != nil {
+			return
+		}
+		for path, content := range files {
+			var kind ChangeKind
+			if base, ok := baseline[path]; ok {
+				if bytes.Equal(base, content) {
+					continue
+				}
+				kind = ChangeKind_Modified
+			} else if orig, readErr := ioutil.ReadFile(path); readErr == nil {
+				// copied up without modification
+				if bytes.Equal(orig, content) {
+					continue
+				}
+				kind = ChangeKind_Modified
+			} else {
+				kind = ChangeKind_Added
+			}
+			changes = append(changes, &Change{Path: path, Kind: kind})
+		}
+		for path := range baseline {
+			if _, ok := files[path]; !ok {
+				// seeded file removed
+				changes = append(changes, &Change{Path: path, Kind: ChangeKind_Removed})
+			}
+		}
+		for _, path := range deleted {
+			if baselineDeleted[path] {
+				continue
+			}
+			if _, ok := files[path]; ok {
+				// re-created
+				continue
+			}
+			info, statErr := os.Stat(path)
+			if statErr != nil || info.IsDir() {
+				continue
+			}
+			changes = append(changes, &Change{Path: path, Kind: ChangeKind_Removed})
+		}
+	})
+	if err != nil {
+		c.t.Fatalf("fsfake: %v", err)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+// Cancel removes the mocks and the overlay
+func (c *FS) Cancel() {
+	for _, cancel := range c.cancels {
+		cancel()
+	}
+	c.cancels = nil
+	trap.Direct(func() {
+		os.RemoveAll(c.root)
+	})
+}
+
+func (c *FS) openFile(name string, flag int, perm os.FileMode) (file *os.File, err error) {
+	trap.Direct(func() {
+		var path string
+		if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+			path, err = c.prepareWrite(c.abs(name), flag&os.O_TRUNC == 0)
+			if err != nil {
+				return
+			}
+		} else {
+			path = c.resolve(c.abs(name))
+		}
+		file, err = os.OpenFile(path, flag, perm)
+	})
+	return file, fixPathErr(err, name)
+}
+
+func (c *FS) readFile(name string) (data []byte, err error) {
+	trap.Direct(func() {
+		data, err = ioutil.ReadFile(c.resolve(c.abs(name)))
+	})
+	return data, fixPathErr(err, name)
+}
+
+func (c *FS) writeFile(name string, data []byte, perm os.FileMode) (err error) {
+	trap.Direct(func() {
+		var path string
+		path, err = c.prepareWrite(c.abs(name), false)
+		if err != nil {
+			return
+		}
+		err = ioutil.WriteFile(path, data, perm)
+	})
+	return fixPathErr(err, name)
+}
+
+func (c *FS) stat(name string) (info os.FileInfo, err error) {
+	trap.Direct(func() {
+		info, err = os.Stat(c.resolve(c.abs(name)))
+	})
+	return info, fixPathErr(err, name)
+}
+
+func (c *FS) lstat(name string) (info os.FileInfo, err error) {
+	trap.Direct(func() {
+		info, err = os.Lstat(c.resolve(c.abs(name)))
+	})
+	return info, fixPathErr(err, name)
+}
+
+func (c *FS) remove(name string) (err error) {
+	trap.Direct(func() {
+		abs := c.abs(name)
+		upper := c.upperPath(abs)
+		_, upperErr := os.Lstat(upper)
+		lowerVisible := false
+		if !c.isDeleted(abs) {
+			_, lowerErr := os.Lstat(abs)
+			lowerVisible = lowerErr == nil
+		}
+		if upperErr != nil && !lowerVisible {
+			err = &os.PathError{Op: "remove", Path: name, Err: syscall.ENOENT}
+			return
+		}
+		info, statErr := os.Lstat(c.resolve(abs))
+		if statErr == nil && info.IsDir() {
+			names, listErr := c.listDir(abs)
+			if listErr == nil && len(names) > 0 {
+				err = &os.PathError{Op: "remove", Path: name, Err: syscall.ENOTEMPTY}
+				return
+			}
+		}
+		if upperErr == nil {
+			err = os.Remove(upper)
+			if err != nil {
+				return
+			}
+		}
+		if lowerVisible {
+			c.mutex.Lock()
+			c.deleted[abs] = true
+			c.mutex.Unlock()
+		}
+	})
+	return fixPathErr(err, name)
+}
+
+func (c *FS) removeAll(path string) (err error) {
+	trap.Direct(func() {
+		abs := c.abs(path)
+		err = os.RemoveAll(c.upperPath(abs))
+		if err != nil {
+			return
+		}
+		if c.isDeleted(abs) {
+			return
+		}
+		if _, lowerErr := os.Lstat(abs); lowerErr == nil {
+			c.mutex.Lock()
+			c.deleted[abs] = true
+			c.mutex.Unlock()
+		}
+	})
+	return fixPathErr(err, path)
+}
+
+func (c *FS) mkdir(name string, perm os.FileMode) (err error) {
+	trap.Direct(func() {
+		abs := c.abs(name)
+		if _, statErr := os.Lstat(c.resolve(abs)); statErr == nil {
+			err = &os.PathError{Op: "mkdir", Path: name, Err: syscall.EEXIST}
+			return
+		}
+		parent := filepath.Dir(abs)
+		info, statErr := os.Stat(c.resolve(parent))
+		if statErr != nil {
+			err = &os.PathError{Op: "mkdir", Path: name, Err: syscall.ENOENT}
+			return
+		}
+		if !info.IsDir() {
+			err = &os.PathError{Op: "mkdir", Path: name, Err: syscall.ENOTDIR}
+			return
+		}
+		err = os.MkdirAll(c.upperPath(parent), 0755)
+		if err != nil {
+			return
+		}
+		err = os.Mkdir(c.upperPath(abs), perm)
+	})
+	return fixPathErr(err, name)
+}
+
+func (c *FS) mkdirAll(path string, perm os.FileMode) (err error) {
+	trap.Direct(func() {
+		abs := c.abs(path)
+		// the first existing ancestor must be a dir
+		for dir := abs; ; dir = filepath.Dir(dir) {
+			info, statErr := os.Stat(c.resolve(dir))
+			if statErr == nil {
+				if !info.IsDir() {
+					err = &os.PathError{Op: "mkdir", Path: path, Err: syscall.ENOTDIR}
+					return
+				}
+				if dir == abs {
+					return
+				}
+				break
+			}
+			if filepath.Dir(dir) == dir {
+				break
+			}
+		}
+		err = os.MkdirAll(c.upperPath(abs), perm)
+	})
+	return fixPathErr(err, path)
+}
+
+func (c *FS) readDirInfo(dirname string) (infos []os.FileInfo, err error) {
+	trap.Direct(func() {
+		abs := c.abs(dirname)
+		var names []string
+		names, err = c.listDir(abs)
+		if err != nil {
+			return
+		}
+		infos = make([]os.FileInfo, 0, len(names))
+		for _, name := range names {
+			var info os.FileInfo
+			info, err = os.Lstat(c.resolve(filepath.Join(abs, name)))
+			if err != nil {
+				return
+			}
+			infos = append(infos, info)
+		}
+	})
+	return infos, fixPathErr(err, dirname)
+}
+
+// listDir returns sorted names of entries in both
+// overlay and real disk, except removed ones
+func (c *FS) listDir(abs string) ([]string, error) {
+	upperInfos, upperErr := ioutil.ReadDir(c.upperPath(abs))
+	var lowerInfos []os.FileInfo
+	lowerErr := upperErr
+	if !c.isDeleted(abs) {
+		lowerInfos, lowerErr = ioutil.ReadDir(abs)
+	}
+	if upperErr != nil && lowerErr != nil {
+		if _, err := os.Lstat(c.upperPath(abs)); err == nil {
+			return nil, upperErr
+		}
+		return nil, lowerErr
+	}
+	seen := make(map[string]bool, len(upperInfos)+len(lowerInfos))
+	var names []string
+	for _, info := range upperInfos {
+		seen[info.Name()] = true
+		names = append(names, info.Name())
+	}
+	for _, info := range lowerInfos {
+		name := info.Name()
+		if seen[name] || c.isDeleted(filepath.Join(abs, name)) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// resolve returns the path to read `abs` from
+func (c *FS) resolve(abs string) string {
+	upper := c.upperPath(abs)
+	if _, err := os.Lstat(upper); err == nil || c.isDeleted(abs) {
+		return upper
+	}
+	return abs
+}
+
+// prepareWrite copies `abs` up to the overlay if it is
+// a real file and `keep` is true, and ensures its parent
+// dir exists in the overlay
+func (c *FS) prepareWrite(abs string, keep bool) (string, error) {
+	upper := c.upperPath(abs)
+	if _, err := os.Lstat(upper); err == nil {
+		return upper, nil
+	}
+	parent := filepath.Dir(abs)
+	if info, err := os.Stat(c.resolve(parent)); err == nil && info.IsDir() {
+		err := os.MkdirAll(c.upperPath(parent), 0755)
+		if err != nil {
+			return "", err
+		}
+	}
+	if c.isDeleted(abs) {
+		return upper, nil
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return upper, nil
+	}
+	if info.IsDir() {
+		return upper, os.MkdirAll(upper, info.Mode().Perm())
+	}
+	if keep {
+		err := copyFile(abs, upper, info.Mode())
+		if err != nil {
+			return "", err
+		}
+	}
+	return upper, nil
+}
+
+func (c *FS) isDeleted(abs string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for path := abs; ; path = filepath.Dir(path) {
+		if c.deleted[path] {
+			return true
+		}
+		if filepath.Dir(path) == path {
+			return false
+		}
+	}
+}
+
+// must be called inside trap.Direct because
+// filepath.Abs calls os.Getwd
+func (c *FS) abs(name string) string {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return filepath.Clean(name)
+	}
+	return abs
+}
+
+func (c *FS) upperPath(abs string) string {
+	return filepath.Join(c.root, abs[len(filepath.VolumeName(abs)):])
+}
+
+// upperFiles reads all regular files in the overlay,
+// keyed by path seen by the code under test
+func (c *FS) upperFiles() (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	volume := filepath.VolumeName(c.root)
+	err := filepath.Walk(c.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		abs := volume + strings.TrimPrefix(path, c.root)
+		files[abs] = content
+		return nil
+	})
+	return files, err
+}
+
+func (c *FS) snapshot() {
+	var files map[string][]byte
+	var err error
+	trap.Direct(func() {
+		files, err = c.upperFiles()
+	})
+	if err != nil {
+		c.t.Fatalf("fsfake: %v", err)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.baseline = files
+	c.baselineDeleted = make(map[string]bool, len(c.deleted))
+	for path := range c.deleted {
+		c.baselineDeleted[path] = true
+	}
+}
+
+// must be called inside trap.Direct
+func copyFile(src string, dst string, mode os.FileMode) error {
+	err := os.MkdirAll(filepath.Dir(dst), 0755)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	closeErr := out.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// fixPathErr replaces overlay path in err with `name`
+func fixPathErr(err error, name string) error {
+	if pathErr, ok := err.(*os.PathError); ok {
+		pathErr.Path = name
+	}
+	return err
+}
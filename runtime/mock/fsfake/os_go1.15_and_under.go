@@ -0,0 +1,10 @@
+//go:build !go1.16
+// +build !go1.16
+
+package fsfake
+
+// os.ReadFile, os.WriteFile and os.ReadDir
+// are added in go1.16
+func (c *FS) patchGo116() []func() {
+	return nil
+}
@@ -0,0 +1,49 @@
+//go:build go1.16
+// +build go1.16
+
+package fsfake
+
+import (
+	"os"
+
+	"github.com/xhd2015/xgo/runtime/mock"
+	"github.com/xhd2015/xgo/runtime/trap"
+)
+
+func (c *FS) patchGo116() []func() {
+	return []func(){
+		mock.Patch(os.ReadFile, c.readFile),
+		mock.Patch(os.WriteFile, c.writeFile),
+		mock.Patch(os.ReadDir, c.readDir),
+	}
+}
+
+func (c *FS) readDir(name string) (entries []os.DirEntry, err error) {
+	trap.Direct(func() {
+		abs := c.abs(name)
+		var names []string
+		names, err = c.listDir(abs)
+		if err != nil {
+			return
+		}
+		// read each side once, entries in upper shadow real ones
+		byName := make(map[string]os.DirEntry, len(names))
+		if !c.isDeleted(abs) {
+			lowerEntries, _ := os.ReadDir(abs)
+			for _, entry := range lowerEntries {
+				byName[entry.Name()] = entry
+			}
+		}
+		upperEntries, _ := os.ReadDir(c.upperPath(abs))
+		for _, entry := range upperEntries {
+			byName[entry.Name()] = entry
+		}
+		entries = make([]os.DirEntry, 0, len(names))
+		for _, entryName := range names {
+			if entry, ok := byName[entryName]; ok {
+				entries = append(entries, entry)
+			}
+		}
+	})
+	return entries, fixPathErr(err, name)
+}
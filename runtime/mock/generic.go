@@ -0,0 +1,51 @@
+package mock
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/xhd2015/xgo/runtime/trap"
+)
+
+// MockGeneric setup mock on all instantiations of
+// the generic function or method `fn`, which can be
+// any instantiation, e.g. `ToString[int]`.
+// Unlike Mock, which only matches the given
+// instantiation, the interceptor receives calls to
+// `ToString[string]` as well. Use IsInstance or TypeArgs
+// to tell which instantiation is called.
+func MockGeneric(fn interface{}, interceptor Interceptor, opts ...Option) func() {
+	recvPtr, fnInfo, _, _ := getFunc(fn)
+	if !fnInfo.Generic {
+		panic(fmt.Errorf("failed to setup mock for: %s, not a generic function", fnInfo.DisplayName()))
+	}
+	return mockWithOptions(recvPtr, fnInfo, 0, 0, interceptor, opts)
+}
+
+// IsInstance reports whether the call being intercepted
+// is the instantiation `fn` of a generic function,
+// it should be called inside an interceptor.
+//
+// Example:
+//
+//	mock.MockGeneric(ToString[int], func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+//		if mock.IsInstance(ToString[string]) {
+//			return mock.ErrCallOld
+//		}
+//		...
+//	})
+func IsInstance(fn interface{}) bool {
+	pc := trap.GetTrappingPC()
+	if pc == 0 {
+		return false
+	}
+	_, _, funcPC, trappingPC := trap.InspectPC(fn)
+	return pc == funcPC || pc == trappingPC
+}
+
+// TypeArgs returns type arguments of the generic call being
+// intercepted, i.e. [int] for ToString[int], it should be
+// called inside an interceptor.
+func TypeArgs() []reflect.Type {
+	return trap.GetTypeArgs()
+}
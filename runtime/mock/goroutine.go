@@ -0,0 +1,128 @@
+package mock
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/xhd2015/xgo/runtime/trap"
+)
+
+// GoMode tells how Goroutines handles go statements
+type GoMode int
+
+const (
+	// RunInline runs the function of a go statement
+	// synchronously, the go statement returns after
+	// the function returns
+	RunInline GoMode = iota + 1
+
+	// Capture holds the function of a go statement
+	// until Drain is called
+	Capture
+)
+
+// GoroutineControl takes over go statements, see Goroutines
+type GoroutineControl struct {
+	mode   GoMode
+	cancel func()
+
+	mutex   sync.Mutex
+	count   int
+	pending []func()
+}
+
+// Goroutines takes over go statements executed by current
+// goroutine, so code firing `go f()` in background can be
+// tested deterministically. Functions run inline or drained
+// execute on current goroutine, so go statements inside them
+// are taken over too.
+// Like Mock, it only affects current goroutine, and is released
+// when current goroutine exits or Cancel is called. Only go
+// statements of the main module are taken over, goroutines
+// started by the standard library and dependencies, i.e.
+// connection loops of net/http, run as usual.
+// It requires go1.18 and above, otherwise
+// trap.ErrGoHandlerUnsupported is returned.
+//
+// Example:
+//
+//	g, err := mock.Goroutines(mock.Capture)
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	svc.Notify(user)
+//	if g.Count() != 1 {
+//		t.Fatalf("expect 1 background job, actual: %d", g.Count())
+//	}
+//	g.Drain()
+func Goroutines(mode GoMode) (*GoroutineControl, error) {
+	if mode != RunInline && mode != Capture {
+		panic(fmt.Errorf("unknown go mode: %d", mode))
+	}
+	c := &GoroutineControl{mode: mode}
+	cancel, err := trap.SetGoHandler(c.handle)
+	if err != nil {
+		return nil, err
+	}
+	c.cancel = cancel
+	return c, nil
+}
+
+func (c *GoroutineControl) handle(fn func()) bool {
+	c.mutex.Lock()
+	c.count++
+	if c.mode == Capture {
+		c.pending = append(c.pending, fn)
+	}
+	c.mutex.Unlock()
+
+	if c.mode == RunInline {
+		fn()
+	}
+	return true
+}
+
+// Count returns the number of go statements taken over
+func (c *GoroutineControl) Count() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.count
+}
+
+// Pending returns the number of captured
+// functions not yet run by Drain
+func (c *GoroutineControl) Pending() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.pending)
+}
+
+// Drain runs captured functions on current goroutine
+// in the order of their go statements, including
+// those captured while draining, until none is left.
+// It returns the number of functions run.
+func (c *GoroutineControl) Drain() int {
+	n := 0
+	for {
+		c.mutex.Lock()
+		if len(c.pending) == 0 {
+			c.mutex.Unlock()
+			return n
+		}
+		fn := c.pending[0]
+		c.pending = c.pending[1:]
+		c.mutex.Unlock()
+
+		fn()
+		n++
+	}
+}
+
+// Cancel restores go statements to start goroutines,
+// captured functions not drained are discarded.
+func (c *GoroutineControl) Cancel() {
+	c.cancel()
+	c.mutex.Lock()
+	c.pending = nil
+	c.mutex.Unlock()
+}
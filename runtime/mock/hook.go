@@ -0,0 +1,19 @@
+package mock
+
+import (
+	"fmt"
+	"os"
+	"unsafe"
+)
+
+// link by compiler
+func __xgo_link_getcurg() unsafe.Pointer {
+	fmt.Fprintln(os.Stderr, "WARNING: failed to link __xgo_link_getcurg(requires xgo).")
+	return nil
+}
+
+// link by compiler
+func __xgo_link_peek_panic() interface{} {
+	fmt.Fprintln(os.Stderr, "WARNING: failed to link __xgo_link_peek_panic(requires xgo).")
+	return nil
+}
@@ -0,0 +1,285 @@
+// Package httpfake stubs outbound HTTP requests made
+// through (*http.Client).Do, which http.Get, http.Head,
+// http.Post and methods of http.Client all go through.
+//
+// Example:
+//
+//	fake := httpfake.Install(t)
+//	fake.On("GET", "https://api.example.com/users/*").
+//		Header("Content-Type", "application/json").
+//		Reply(200, `{"name":"test"}`)
+//
+//	// code under test...
+//
+//	if n := len(fake.Requests()); n != 1 {
+//		t.Fatalf("expect 1 request, actual: %d", n)
+//	}
+package httpfake
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/core"
+	"github.com/xhd2015/xgo/runtime/mock"
+)
+
+// Fake holds a route table, requests matching
+// no route fail the test.
+type Fake struct {
+	t      testing.TB
+	cancel func()
+
+	mutex    sync.Mutex
+	routes   []*Route
+	requests []*Request
+}
+
+// Route describes the response of requests
+// matching method and URL pattern
+type Route struct {
+	method  string
+	pattern string
+
+	status  int
+	header  http.Header
+	body    []byte
+	handler http.HandlerFunc
+	err     error
+}
+
+// Request is a logged outbound request
+type Request struct {
+	Method string
+	URL    *url.URL
+	Header http.Header
+	Body   []byte
+
+	// Matched is false if no route matched
+	Matched bool
+}
+
+// Install stubs outbound HTTP requests, the scope is the
+// same as mock.Mock, so parallel tests are not affected.
+func Install(t testing.TB) *Fake {
+	if t == nil {
+		panic("t cannot be nil")
+	}
+	c := &Fake{t: t}
+	c.cancel = mock.Mock((*http.Client).Do, c.intercept)
+	t.Cleanup(c.Cancel)
+	return c
+}
+
+// On adds a route, `method` can be empty or "*" to match
+// any method.
+// `urlPattern` is matched against the URL without query,
+// where '*' matches any characters. If `urlPattern` has
+// no scheme, it is matched against host and path, i.e.
+// "api.example.com/users/*". If `urlPattern` contains
+// '?', the query is also matched.
+// Routes are matched in the order they are added.
+func (c *Fake) On(method string, urlPattern string) *Route {
+	r := &Route{
+		method:  strings.ToUpper(method),
+		pattern: urlPattern,
+		status:  http.StatusOK,
+		header:  make(http.Header),
+	}
+	c.mutex.Lock()
+	c.routes = append(c.routes, r)
+	c.mutex.Unlock()
+	return r
+}
+
+// Requests returns all logged requests in order,
+// including unmatched ones
+func (c *Fake) Requests() []*Request {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	requests := make([]*Request, len(c.requests))
+	copy(requests, c.requests)
+	return requests
+}
+
+// Unmatched returns requests that matched no route
+func (c *Fake) Unmatched() []*Request {
+	var unmatched []*Request
+	for _, req := range c.Requests() {
+		if !req.Matched {
+			unmatched = append(unmatched, req)
+		}
+	}
+	return unmatched
+}
+
+// Cancel removes the mock, it is automatically
+// called when the test finishes.
+func (c *Fake) Cancel() {
+	c.cancel()
+}
+
+// Header adds a response header
+func (r *Route) Header(key string, value string) *Route {
+	r.header.Add(key, value)
+	return r
+}
+
+// Reply sets response status and body
+func (r *Route) Reply(status int, body string) *Route {
+	r.status = status
+	r.body = []byte(body)
+	return r
+}
+
+// Handle lets `handler` write the response,
+// status, headers and body set by Reply and
+// Header are ignored.
+func (r *Route) Handle(handler http.HandlerFunc) *Route {
+	if handler == nil {
+		panic("handler cannot be nil")
+	}
+	r.handler = handler
+	return r
+}
+
+// Fail makes matched requests fail with `err`,
+// like a network error.
+func (r *Route) Fail(err error) *Route {
+	if err == nil {
+		panic("err cannot be nil")
+	}
+	r.err = err
+	return r
+}
+
+func (c *Fake) intercept(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+	req, _ := args.GetFieldIndex(1).Value().(*http.Request)
+	if req == nil || req.URL == nil {
+		return mock.ErrCallOld
+	}
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			results.(core.ObjectWithErr).GetErr().Set(wrapErr(req, err))
+			return nil
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	logged := &Request{
+		Method: req.Method,
+		URL:    req.URL,
+		Header: req.Header.Clone(),
+		Body:   body,
+	}
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	c.mutex.Lock()
+	var route *Route
+	for _, r := range c.routes {
+		if r.match(method, req.URL) {
+			route = r
+			break
+		}
+	}
+	logged.Matched = route != nil
+	c.requests = append(c.requests, logged)
+	c.mutex.Unlock()
+
+	if route == nil {
+		c.t.Errorf("httpfake: unmatched request %s %s", method, req.URL)
+		results.(core.ObjectWithErr).GetErr().Set(wrapErr(req, fmt.Errorf("httpfake: no route for %s %s", method, req.URL)))
+		return nil
+	}
+	if route.err != nil {
+		results.(core.ObjectWithErr).GetErr().Set(wrapErr(req, route.err))
+		return nil
+	}
+	results.GetFieldIndex(0).Set(route.respond(req))
+	return nil
+}
+
+func (r *Route) match(method string, u *url.URL) bool {
+	if r.method != "" && r.method != "*" && r.method != method {
+		return false
+	}
+	target := u.Host + u.EscapedPath()
+	if strings.Contains(r.pattern, "://") {
+		target = u.Scheme + "://" + target
+	}
+	if strings.Contains(r.pattern, "?") {
+		target += "?" + u.RawQuery
+	}
+	return matchWildcard(target, r.pattern)
+}
+
+func (r *Route) respond(req *http.Request) *http.Response {
+	if r.handler != nil {
+		rec := httptest.NewRecorder()
+		r.handler(rec, req)
+		resp := rec.Result()
+		resp.Request = req
+		return resp
+	}
+	header := r.header.Clone()
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.status, http.StatusText(r.status)),
+		StatusCode:    r.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(r.body)),
+		ContentLength: int64(len(r.body)),
+		Request:       req,
+	}
+}
+
+// wrapErr wraps err in the same way as http.Client
+func wrapErr(req *http.Request, err error) error {
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	return &url.Error{
+		Op:  method[:1] + strings.ToLower(method[1:]),
+		URL: req.URL.String(),
+		Err: err,
+	}
+}
+
+// matchWildcard matches s against pattern,
+// where '*' matches any sequence of characters
+func matchWildcard(s string, pattern string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return s == pattern
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		idx := strings.Index(s, part)
+		if idx < 0 {
+			return false
+		}
+		s = s[idx+len(part):]
+	}
+	return len(s) >= len(last) && strings.HasSuffix(s, last)
+}
@@ -0,0 +1,70 @@
+package mock
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/xhd2015/xgo/runtime/functab"
+)
+
+// MockInterface setup mock on `method` of every concrete
+// type that implements the interface pointed by `ifacePtr`,
+// see MockInterfaceByName. A type safe version is
+// typed.MockInterface.
+//
+// Example:
+//
+//	mock.MockInterface((*Store)(nil), "Save", interceptor)
+func MockInterface(ifacePtr interface{}, method string, interceptor Interceptor) func() {
+	t := reflect.TypeOf(ifacePtr)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Interface {
+		panic(fmt.Errorf("requires pointer to interface, given: %T", ifacePtr))
+	}
+	return mockInterface(t.Elem(), method, interceptor)
+}
+
+// MockInterfaceByName setup mock on `method` of every concrete
+// type that implements the interface `interfaceName` declared
+// in `pkgPath`.
+// Like MockByName on a method, the receiver is passed
+// to the interceptor as the first arg.
+// NOTE: only types from instrumented packages are affected,
+// generic types are not supported.
+func MockInterfaceByName(pkgPath string, interfaceName string, method string, interceptor Interceptor) func() {
+	intfType := functab.GetInterfaceType(pkgPath, interfaceName)
+	if intfType == nil {
+		panic(fmt.Errorf("failed to setup mock for interface: %s.%s", pkgPath, interfaceName))
+	}
+	return mockInterface(intfType, method, interceptor)
+}
+
+func mockInterface(intfType reflect.Type, method string, interceptor Interceptor) func() {
+	if intfType.Kind() != reflect.Interface {
+		panic(fmt.Errorf("requires interface, given: %s", intfType.String()))
+	}
+	if _, ok := intfType.MethodByName(method); !ok {
+		panic(fmt.Errorf("interface %s has no method: %s", intfType.String(), method))
+	}
+
+	var cancels []func()
+	for recvType, methods := range functab.GetTypesMethods() {
+		fnInfo := methods[method]
+		if fnInfo == nil {
+			continue
+		}
+		if !recvType.Implements(intfType) {
+			if recvType.Kind() == reflect.Ptr || !reflect.PtrTo(recvType).Implements(intfType) {
+				continue
+			}
+		}
+		cancels = append(cancels, mock(nil, fnInfo, 0, 0, interceptor))
+	}
+	if len(cancels) == 0 {
+		panic(fmt.Errorf("failed to setup mock for %s.%s: no implementation found", intfType.String(), method))
+	}
+	return func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,449 @@
+package mock
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"unsafe"
+
+	"github.com/xhd2015/xgo/runtime/core"
+	"github.com/xhd2015/xgo/runtime/functab"
+	"github.com/xhd2015/xgo/runtime/internal/testhook"
+)
+
+// XGO_REPORT_VAR_MUTATION is set by `xgo test --report-var-mutation`,
+// valid values: 1, true, on
+const XGO_REPORT_VAR_MUTATION = "XGO_REPORT_VAR_MUTATION"
+
+func init() {
+	switch os.Getenv(XGO_REPORT_VAR_MUTATION) {
+	case "1", "true", "on":
+	default:
+		return
+	}
+	testhook.OnTestStart(func(t *testing.T, fn func(t *testing.T)) {
+		// subtests are attributed to their top level test
+		if strings.Contains(t.Name(), "/") {
+			return
+		}
+		states := stateVars()
+		t.Cleanup(func() {
+			var mutated []string
+			for _, s := range states {
+				if s.mutated() {
+					mutated = append(mutated, s.fn.Pkg+"."+s.fn.IdentityName)
+				}
+			}
+			if len(mutated) == 0 {
+				return
+			}
+			sort.Strings(mutated)
+			fmt.Fprintf(os.Stderr, "xgo: %s mutated package variables:\n  %s\n", t.Name(), strings.Join(mutated, "\n  "))
+		})
+	})
+}
+
+// IsolateVars deep copies package variables whose package
+// matches any of `pkgPatterns` when called, and restores
+// them when `t` finishes, so mutations made by `t` do not
+// leak into other tests. Without patterns, all variables
+// are isolated.
+// A pattern is a package path, or `path/...` to include
+// sub packages. Only variables of the main module are
+// available, see MOCK_VAR_CONST.md.
+//
+// Values are restored in place: pointers, maps and slices
+// held by variables keep their identity, while the data
+// they refer to is restored. Functions, channels and data
+// holding sync primitives, like *sql.DB or *sync.Pool, are
+// restored by reference only, variables holding them by
+// value are not isolated.
+// Variables are copied and restored without locking, so
+// they must not be used by other goroutines meanwhile.
+//
+// Example:
+//
+//	func TestRegister(t *testing.T) {
+//		mock.IsolateVars(t, "github.com/acme/registry/...")
+//		registry.Register("x", handler)
+//	}
+func IsolateVars(t testing.TB, pkgPatterns ...string) {
+	if t == nil {
+		panic("t cannot be nil")
+	}
+	snapshots := snapshotVars(pkgPatterns)
+	t.Cleanup(func() {
+		for _, s := range snapshots {
+			s.restore()
+		}
+	})
+}
+
+type varSnapshot struct {
+	fn *core.FuncInfo
+	v  reflect.Value // the variable
+
+	copy    reflect.Value
+	origins map[refKey]reflect.Value // copied pointer, map or slice -> original
+}
+
+// refKey identifies a pointer, map or slice, the
+// type tells a struct from its first field
+type refKey struct {
+	p uintptr
+	t reflect.Type
+}
+
+func refKeyOf(v reflect.Value) refKey {
+	return refKey{p: v.Pointer(), t: v.Type()}
+}
+
+func snapshotVars(pkgPatterns []string) []*varSnapshot {
+	var snapshots []*varSnapshot
+	for _, fn := range getVars(pkgPatterns) {
+		v := reflect.ValueOf(fn.Var).Elem()
+		c := &copier{
+			copies:  make(map[refKey]reflect.Value),
+			origins: make(map[refKey]reflect.Value),
+		}
+		snapshots = append(snapshots, &varSnapshot{
+			fn:      fn,
+			v:       v,
+			copy:    c.deepCopy(v),
+			origins: c.origins,
+		})
+	}
+	return snapshots
+}
+
+// varState is the shallow state of a variable, the data it
+// refers to is not read, so that tests and goroutines running
+// meanwhile can keep using it
+type varState struct {
+	fn     *core.FuncInfo
+	v      reflect.Value
+	value  reflect.Value
+	mapLen int // len of the map held by the variable
+}
+
+func stateVars() []*varState {
+	var states []*varState
+	for _, fn := range getVars(nil) {
+		v := reflect.ValueOf(fn.Var).Elem()
+		s := &varState{
+			fn:    fn,
+			v:     v,
+			value: detach(accessible(v)),
+		}
+		if v.Kind() == reflect.Map {
+			s.mapLen = v.Len()
+		}
+		states = append(states, s)
+	}
+	return states
+}
+
+// mutated reports assignments to the variable, and
+// entries added to or removed from the map it holds
+func (c *varState) mutated() bool {
+	if c.v.Kind() == reflect.Map && c.v.Len() != c.mapLen {
+		return true
+	}
+	return !shallowEqual(c.v, c.value)
+}
+
+// getVars returns registered variables that are safe to
+// copy by value: variables holding sync primitives by value
+// change with their use by other goroutines
+func getVars(pkgPatterns []string) []*core.FuncInfo {
+	var vars []*core.FuncInfo
+	for _, fn := range functab.GetFuncs() {
+		if fn.Kind != core.Kind_Var || fn.Var == nil {
+			continue
+		}
+		if isXgoRuntimePkg(fn.Pkg) {
+			continue
+		}
+		if len(pkgPatterns) > 0 && !matchAnyPkg(fn.Pkg, pkgPatterns) {
+			continue
+		}
+		if holdsSync(reflect.TypeOf(fn.Var).Elem()) {
+			continue
+		}
+		vars = append(vars, fn)
+	}
+	return vars
+}
+
+var holdsSyncCache sync.Map // reflect.Type -> bool
+
+// holdsSync reports whether a value of `t` contains, not
+// through pointers, a type of package sync or sync/atomic
+func holdsSync(t reflect.Type) bool {
+	if v, ok := holdsSyncCache.Load(t); ok {
+		return v.(bool)
+	}
+	var res bool
+	switch t.PkgPath() {
+	case "sync", "sync/atomic":
+		res = true
+	default:
+		switch t.Kind() {
+		case reflect.Struct:
+			for i := 0; i < t.NumField(); i++ {
+				if holdsSync(t.Field(i).Type) {
+					res = true
+					break
+				}
+			}
+		case reflect.Array:
+			res = holdsSync(t.Elem())
+		}
+	}
+	holdsSyncCache.Store(t, res)
+	return res
+}
+
+// xgo runtime keeps its own state in package variables,
+// which are registered when testing xgo runtime itself
+func isXgoRuntimePkg(pkg string) bool {
+	return strings.HasPrefix(pkg, "github.com/xhd2015/xgo/runtime/") && !strings.HasPrefix(pkg, "github.com/xhd2015/xgo/runtime/test/")
+}
+
+func matchAnyPkg(pkg string, pkgPatterns []string) bool {
+	for _, pattern := range pkgPatterns {
+		if core.MatchPkg(pkg, "", pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *varSnapshot) restore() {
+	r := &restorer{
+		origins: c.origins,
+		visited: make(map[refKey]bool),
+	}
+	r.restore(c.v, c.copy)
+}
+
+// accessible makes values of unexported fields usable,
+// a non-addressable value is copied to be addressable
+func accessible(v reflect.Value) reflect.Value {
+	if v.CanSet() {
+		return v
+	}
+	if v.CanAddr() {
+		return reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
+	}
+	if v.CanInterface() {
+		tmp := reflect.New(v.Type()).Elem()
+		tmp.Set(v)
+		return tmp
+	}
+	return v
+}
+
+// detach copies v so that later assignments
+// to where v is stored do not affect it
+func detach(v reflect.Value) reflect.Value {
+	res := reflect.New(v.Type()).Elem()
+	res.Set(v)
+	return res
+}
+
+type copier struct {
+	copies  map[refKey]reflect.Value // original pointer or map -> copy
+	origins map[refKey]reflect.Value
+}
+
+// deepCopy copies data reachable from v, except funcs,
+// channels, unsafe pointers and data holding sync
+// primitives, cycles are preserved
+func (c *copier) deepCopy(v reflect.Value) reflect.Value {
+	v = accessible(v)
+	t := v.Type()
+	res := reflect.New(t).Elem()
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if !v.IsNil() && holdsSync(t.Elem()) {
+			// shared with other goroutines, keep the reference
+			res.Set(v)
+			return res
+		}
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map:
+		if v.IsNil() {
+			return res
+		}
+		if p, ok := c.copies[refKeyOf(v)]; ok {
+			return p
+		}
+		if v.Kind() == reflect.Ptr {
+			res = reflect.New(t.Elem())
+		} else {
+			res = reflect.MakeMapWithSize(t, v.Len())
+		}
+		c.copies[refKeyOf(v)] = res
+		c.origins[refKeyOf(res)] = detach(v)
+		if v.Kind() == reflect.Ptr {
+			res.Elem().Set(c.deepCopy(v.Elem()))
+			return res
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			res.SetMapIndex(iter.Key(), c.deepCopy(iter.Value()))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			accessible(res.Field(i)).Set(c.deepCopy(v.Field(i)))
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			res.Index(i).Set(c.deepCopy(v.Index(i)))
+		}
+	case reflect.Slice:
+		if v.IsNil() {
+			return res
+		}
+		res = reflect.MakeSlice(t, v.Len(), v.Cap())
+		if v.Cap() > 0 {
+			c.origins[refKeyOf(res)] = detach(v)
+		}
+		for i := 0; i < v.Len(); i++ {
+			res.Index(i).Set(c.deepCopy(v.Index(i)))
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return res
+		}
+		res.Set(c.deepCopy(v.Elem()))
+	default:
+		res.Set(v)
+	}
+	return res
+}
+
+type restorer struct {
+	origins map[refKey]reflect.Value
+	visited map[refKey]bool
+}
+
+// restore writes `src`, a deep copy, into `dst`, replacing
+// copied pointers, maps and slices with the originals, whose
+// content is restored too
+func (c *restorer) restore(dst reflect.Value, src reflect.Value) {
+	dst = accessible(dst)
+	src = accessible(src)
+	switch src.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return
+		}
+		orig, ok := c.origins[refKeyOf(src)]
+		if !ok {
+			// kept by reference, or an empty slice
+			dst.Set(src)
+			return
+		}
+		orig = accessible(orig)
+		dst.Set(orig)
+		key := refKeyOf(orig)
+		if c.visited[key] {
+			return
+		}
+		c.visited[key] = true
+		switch src.Kind() {
+		case reflect.Ptr:
+			c.restore(orig.Elem(), src.Elem())
+		case reflect.Map:
+			for _, k := range orig.MapKeys() {
+				orig.SetMapIndex(k, reflect.Value{})
+			}
+			iter := src.MapRange()
+			for iter.Next() {
+				val := reflect.New(iter.Value().Type()).Elem()
+				c.restore(val, iter.Value())
+				orig.SetMapIndex(iter.Key(), val)
+			}
+		case reflect.Slice:
+			for i := 0; i < src.Len(); i++ {
+				c.restore(orig.Index(i), src.Index(i))
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < src.NumField(); i++ {
+			c.restore(dst.Field(i), src.Field(i))
+		}
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			c.restore(dst.Index(i), src.Index(i))
+		}
+	case reflect.Interface:
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return
+		}
+		val := reflect.New(src.Elem().Type()).Elem()
+		c.restore(val, src.Elem())
+		dst.Set(val)
+	default:
+		dst.Set(src)
+	}
+}
+
+// shallowEqual compares values without following
+// pointers, maps and slices are compared by reference
+func shallowEqual(a reflect.Value, b reflect.Value) bool {
+	a = accessible(a)
+	b = accessible(b)
+	switch a.Kind() {
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if !shallowEqual(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Array:
+		for i := 0; i < a.Len(); i++ {
+			if !shallowEqual(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice:
+		return a.Pointer() == b.Pointer() && a.Len() == b.Len() && a.Cap() == b.Cap()
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		if a.Elem().Type() != b.Elem().Type() {
+			return false
+		}
+		return shallowEqual(a.Elem(), b.Elem())
+	case reflect.Ptr, reflect.Map, reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return a.Pointer() == b.Pointer()
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() == b.Uint()
+	case reflect.Float32, reflect.Float64:
+		x, y := a.Float(), b.Float()
+		return x == y || (math.IsNaN(x) && math.IsNaN(y))
+	case reflect.Complex64, reflect.Complex128:
+		return a.Complex() == b.Complex()
+	case reflect.String:
+		return a.String() == b.String()
+	}
+	return false
+}
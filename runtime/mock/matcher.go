@@ -0,0 +1,154 @@
+package mock
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+)
+
+// Matcher checks whether an argument matches
+type Matcher interface {
+	Match(v interface{}) bool
+	String() string
+}
+
+type eqMatcher struct {
+	expect interface{}
+}
+
+type anyMatcher struct{}
+
+type regexMatcher struct {
+	regex *regexp.Regexp
+}
+
+type funcMatcher struct {
+	match func(v interface{}) bool
+}
+
+// Eq matches an argument deeply equal to `v`
+func Eq(v interface{}) Matcher {
+	return eqMatcher{expect: v}
+}
+
+// Any matches any argument
+func Any() Matcher {
+	return anyMatcher{}
+}
+
+// Regex matches a string argument(or a fmt.Stringer)
+// against the pattern.
+// It panics if pattern is not a valid regular expression.
+func Regex(pattern string) Matcher {
+	return regexMatcher{regex: regexp.MustCompile(pattern)}
+}
+
+// MatchFunc matches an argument when `match` returns true
+func MatchFunc(match func(v interface{}) bool) Matcher {
+	if match == nil {
+		panic("match cannot be nil")
+	}
+	return funcMatcher{match: match}
+}
+
+func (c eqMatcher) Match(v interface{}) bool {
+	if c.expect == nil || v == nil {
+		return isNil(c.expect) && isNil(v)
+	}
+	if reflect.DeepEqual(c.expect, v) {
+		return true
+	}
+	// untyped constants like 1 for an int64 argument
+	ev := reflect.ValueOf(c.expect)
+	vt := reflect.TypeOf(v)
+	if !isNumberKind(ev.Kind()) || !isNumberKind(vt.Kind()) {
+		return false
+	}
+	converted := ev.Convert(vt)
+	// reject lossy conversion, i.e. 1.5 -> 1, and
+	// wrapped around ones, i.e. -1 -> math.MaxUint64
+	if converted.Convert(ev.Type()).Interface() != c.expect || numberSign(converted) != numberSign(ev) {
+		return false
+	}
+	return converted.Interface() == v
+}
+
+func (c eqMatcher) String() string {
+	return fmt.Sprintf("Eq(%#v)", c.expect)
+}
+
+func (c anyMatcher) Match(v interface{}) bool {
+	return true
+}
+
+func (c anyMatcher) String() string {
+	return "Any()"
+}
+
+func (c regexMatcher) Match(v interface{}) bool {
+	switch s := v.(type) {
+	case string:
+		return c.regex.MatchString(s)
+	case fmt.Stringer:
+		return c.regex.MatchString(s.String())
+	}
+	return false
+}
+
+func (c regexMatcher) String() string {
+	return fmt.Sprintf("Regex(%q)", c.regex.String())
+}
+
+func (c funcMatcher) Match(v interface{}) bool {
+	return c.match(v)
+}
+
+func (c funcMatcher) String() string {
+	return "MatchFunc(...)"
+}
+
+func isNumberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func numberSign(v reflect.Value) int {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch {
+		case v.Int() < 0:
+			return -1
+		case v.Int() > 0:
+			return 1
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > 0 {
+			return 1
+		}
+	case reflect.Float32, reflect.Float64:
+		switch {
+		case v.Float() < 0:
+			return -1
+		case v.Float() > 0:
+			return 1
+		}
+	}
+	return 0
+}
+
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
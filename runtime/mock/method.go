@@ -0,0 +1,55 @@
+package mock
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/xhd2015/xgo/runtime/functab"
+)
+
+// MockMethod setup mock on method expression `method`,
+// e.g. `(*Client).Do`, the mock applies to all receivers
+// of the type, including those constructed inside the
+// code under test.
+// The receiver is passed to the interceptor as the first arg.
+// To mock a single instance, use Mock with a bound method.
+func MockMethod(method interface{}, interceptor Interceptor, opts ...Option) func() {
+	recvPtr, fnInfo, funcPC, trappingPC := getFunc(method)
+	if fnInfo.RecvType == "" {
+		panic(fmt.Errorf("failed to setup mock for: %s, not a method", fnInfo.DisplayName()))
+	}
+	if recvPtr != nil {
+		panic(fmt.Errorf("failed to setup mock for: %s, expect method expression, actual: bound method", fnInfo.DisplayName()))
+	}
+	return mockWithOptions(nil, fnInfo, funcPC, trappingPC, interceptor, opts)
+}
+
+// MockType setup mock on `method` of all receivers of the
+// type of `v`, usually a zero value such as `(*Client)(nil)`.
+// Unlike MockMethod, it also works for unexported methods.
+// A type safe version is typed.MockType.
+//
+// Example:
+//
+//	mock.MockType((*Client)(nil), "Do", interceptor)
+func MockType(v interface{}, method string, interceptor Interceptor, opts ...Option) func() {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		panic(fmt.Errorf("requires a typed value, given: nil"))
+	}
+	return mockType(t, method, interceptor, opts)
+}
+
+// mockType setup mock on `method` of all receivers of
+// type `t`. For pointer types, methods with value
+// receiver are also looked up.
+func mockType(t reflect.Type, method string, interceptor Interceptor, opts []Option) func() {
+	fn := functab.GetTypeMethods(t)[method]
+	if fn == nil && t.Kind() == reflect.Ptr {
+		fn = functab.GetTypeMethods(t.Elem())[method]
+	}
+	if fn == nil {
+		panic(fmt.Errorf("failed to setup mock for: %s.%s", t, method))
+	}
+	return mockWithOptions(nil, fn, 0, 0, interceptor, opts)
+}
@@ -23,22 +23,24 @@ type Interceptor func(ctx context.Context, fn *core.FuncInfo, args core.Object,
 // if `fn` is a method, only the bound
 // instance will be mocked, other instances
 // are not affected.
+// `opts` can restrict the mock to certain callers,
+// see FromCaller and Within.
 // The returned function can be used to cancel
 // the passed interceptor.
-func Mock(fn interface{}, interceptor Interceptor) func() {
+func Mock(fn interface{}, interceptor Interceptor, opts ...Option) func() {
 	recvPtr, fnInfo, funcPC, trappingPC := getFunc(fn)
-	return mock(recvPtr, fnInfo, funcPC, trappingPC, interceptor)
+	return mockWithOptions(recvPtr, fnInfo, funcPC, trappingPC, interceptor, opts)
 }
 
-func MockByName(pkgPath string, funcName string, interceptor Interceptor) func() {
+func MockByName(pkgPath string, funcName string, interceptor Interceptor, opts ...Option) func() {
 	recv, fn, funcPC, trappingPC := getFuncByName(pkgPath, funcName)
-	return mock(recv, fn, funcPC, trappingPC, interceptor)
+	return mockWithOptions(recv, fn, funcPC, trappingPC, interceptor, opts)
 }
 
 // Can instance be nil?
-func MockMethodByName(instance interface{}, method string, interceptor Interceptor) func() {
+func MockMethodByName(instance interface{}, method string, interceptor Interceptor, opts ...Option) func() {
 	recvPtr, fn, funcPC, trappingPC := getMethodByName(instance, method)
-	return mock(recvPtr, fn, funcPC, trappingPC, interceptor)
+	return mockWithOptions(recvPtr, fn, funcPC, trappingPC, interceptor, opts)
 }
 
 func getFunc(fn interface{}) (recvPtr interface{}, fnInfo *core.FuncInfo, funcPC uintptr, trappingPC uintptr) {
@@ -93,40 +95,36 @@ func getMethodByName(instance interface{}, method string) (recvPtr interface{},
 //   - if mockRecvPtr has a value, then only call to that instance will be mocked
 //   - if mockRecvPtr is nil, then all call to the function will be mocked
 func mock(mockRecvPtr interface{}, mockFnInfo *core.FuncInfo, funcPC uintptr, trappingPC uintptr, interceptor Interceptor) func() {
-	return trap.AddFuncInfoInterceptor(mockFnInfo, &trap.Interceptor{
-		Pre: func(ctx context.Context, f *core.FuncInfo, args, result core.Object) (data interface{}, err error) {
-			if f.Kind == core.Kind_Func && f.PC == 0 {
-				if !f.Generic {
-					if !f.Closure || trap.ClosureHasFunc {
-						return nil, nil
-					}
-				}
-				// may atch generic
-				// or closure without PC
-			}
-			if f != mockFnInfo {
-				// no match
-				return nil, nil
-			}
-			if f.Generic && f.RecvType == "" {
-				// generic function(not method) should distinguish different implementations
-				curTrappingPC := trap.GetTrappingPC()
-				if curTrappingPC != 0 && curTrappingPC != funcPC && curTrappingPC != trappingPC {
-					return nil, nil
-				}
-			}
+	return trap.AddFuncInfoInterceptor(mockFnInfo, newMockInterceptor(mockRecvPtr, mockFnInfo, funcPC, trappingPC, interceptor))
+}
 
-			if f.RecvType != "" && mockRecvPtr != nil {
-				// check recv instance
-				recvPtr := args.GetFieldIndex(0).Ptr()
+func mockWithOptions(mockRecvPtr interface{}, mockFnInfo *core.FuncInfo, funcPC uintptr, trappingPC uintptr, interceptor Interceptor, opts []Option) func() {
+	o := parseOptions(opts)
+	mockRecvPtr, interceptor = o.wrap(mockRecvPtr, interceptor)
+	trapInterceptor := newMockInterceptor(mockRecvPtr, mockFnInfo, funcPC, trappingPC, interceptor)
+	var cancel func()
+	if o.t != nil {
+		cancel = trap.AddTestFuncInfoInterceptor(o.t, mockFnInfo, trapInterceptor)
+	} else {
+		cancel = trap.AddFuncInfoInterceptor(mockFnInfo, trapInterceptor)
+	}
+	if len(o.conds) == 0 {
+		return cancel
+	}
+	untrack := trap.TrackFrames()
+	return func() {
+		cancel()
+		untrack()
+	}
+}
 
-				// check they pointing to the same variable
-				re := reflect.ValueOf(recvPtr).Elem().Interface()
-				me := reflect.ValueOf(mockRecvPtr).Elem().Interface()
-				if re != me {
-					// if *recvPtr != *mockRecvPtr {
-					return nil, nil
-				}
+// newMockInterceptor wraps interceptor as a trap interceptor, see mock() for parameters.
+func newMockInterceptor(mockRecvPtr interface{}, mockFnInfo *core.FuncInfo, funcPC uintptr, trappingPC uintptr, interceptor Interceptor) *trap.Interceptor {
+	match := newCallMatcher(mockRecvPtr, mockFnInfo, funcPC, trappingPC)
+	return &trap.Interceptor{
+		Pre: func(ctx context.Context, f *core.FuncInfo, args, result core.Object) (data interface{}, err error) {
+			if !match(f, args) {
+				return nil, nil
 			}
 
 			// TODO: add panic check
@@ -142,11 +140,83 @@ func mock(mockRecvPtr interface{}, mockFnInfo *core.FuncInfo, funcPC uintptr, tr
 			// when match func, default to use mock
 			return nil, trap.ErrAbort
 		},
-	})
+	}
+}
+
+// newCallMatcher returns a function to check if a trapped call
+// targets the mocked function, see mock() for parameters.
+func newCallMatcher(mockRecvPtr interface{}, mockFnInfo *core.FuncInfo, funcPC uintptr, trappingPC uintptr) func(f *core.FuncInfo, args core.Object) bool {
+	return func(f *core.FuncInfo, args core.Object) bool {
+		if f.Kind == core.Kind_Func && f.PC == 0 {
+			if !f.Generic {
+				if !f.Closure || trap.ClosureHasFunc {
+					return false
+				}
+			}
+			// may atch generic
+			// or closure without PC
+		}
+		if f != mockFnInfo {
+			// no match
+			return false
+		}
+		if f.Generic && f.RecvType == "" && (funcPC != 0 || trappingPC != 0) {
+			// generic function(not method) should distinguish different implementations,
+			// unless all instantiations are mocked, see MockGeneric and MockByName
+			curTrappingPC := trap.GetTrappingPC()
+			if curTrappingPC != 0 && curTrappingPC != funcPC && curTrappingPC != trappingPC {
+				return false
+			}
+		}
+
+		if f.RecvType != "" && mockRecvPtr != nil {
+			// check recv instance
+			recvPtr := args.GetFieldIndex(0).Ptr()
+
+			re := reflect.ValueOf(recvPtr).Elem().Interface()
+			me := reflect.ValueOf(mockRecvPtr).Elem().Interface()
+			if !recvEqual(re, me) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// recvEqual compares pointer-like receivers by identity,
+// and others by value. Receivers that cannot be compared
+// with ==, e.g. structs containing slices or maps, are
+// compared with reflect.DeepEqual.
+func recvEqual(re interface{}, me interface{}) (equal bool) {
+	t := reflect.TypeOf(re)
+	if t != reflect.TypeOf(me) {
+		return false
+	}
+	if t == nil {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return reflect.ValueOf(re).Pointer() == reflect.ValueOf(me).Pointer()
+	}
+	if !t.Comparable() {
+		return reflect.DeepEqual(re, me)
+	}
+	// a comparable struct may still hold non-comparable
+	// values in interface fields
+	defer func() {
+		if recover() != nil {
+			equal = reflect.DeepEqual(re, me)
+		}
+	}()
+	return re == me
 }
 
+// Deprecated: CallOld cannot tell which call to continue and
+// always panics with ErrCallOld. Return ErrCallOld from the
+// interceptor to continue the original call, or use Wrap to
+// call the original function with rewritten arguments.
 func CallOld() {
-	// TODO: implement recover
 	panic(ErrCallOld)
 }
 
@@ -0,0 +1,164 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/core"
+	"github.com/xhd2015/xgo/runtime/internal/testhook"
+	"github.com/xhd2015/xgo/runtime/trap"
+)
+
+// Option customizes a mock. FromCaller, Within, OnlyIf and
+// MatchRecv restrict when the interceptor takes effect,
+// when not satisfied, the original function is called.
+// ForTest changes the scope of the mock.
+type Option func(opts *options)
+
+type options struct {
+	conds     []func(frames []*trap.Frame) bool
+	matchRecv func(recv interface{}) bool
+	t         testing.TB
+}
+
+func init() {
+	// scope of mocks bound by ForTest
+	testhook.OnTestStart(func(t *testing.T, fn func(t *testing.T)) {
+		trap.StartTestScope(t)
+	})
+}
+
+// ForTest binds the mock to test `t` instead of current
+// goroutine: it applies to every goroutine started under
+// `t` and its subtests, including those already started,
+// and is removed when `t` finishes.
+// It requires `t` to be started by xgo test.
+func ForTest(t testing.TB) Option {
+	if t == nil {
+		panic("t cannot be nil")
+	}
+	return func(opts *options) {
+		opts.t = t
+	}
+}
+
+// OnlyIf activates the interceptor only when `cond` returns
+// true, which is given the logical call chain of the
+// intercepted call, starting from the caller, see trap.Frames.
+func OnlyIf(cond func(frames []*trap.Frame) bool) Option {
+	if cond == nil {
+		panic("cond cannot be nil")
+	}
+	return func(opts *options) {
+		opts.conds = append(opts.conds, cond)
+	}
+}
+
+// FromCaller activates the interceptor only when the
+// intercepted function is directly called by `pkgOrFunc`,
+// which is either a package path, `path/...` to include
+// sub packages, or a function. Closures defined inside
+// the function are considered part of it.
+func FromCaller(pkgOrFunc interface{}) Option {
+	match := newFrameMatcher(pkgOrFunc)
+	return OnlyIf(func(frames []*trap.Frame) bool {
+		return len(frames) > 0 && match(frames[0])
+	})
+}
+
+// Within activates the interceptor only when any frame
+// of the call chain matches `pkgOrFunc`, see FromCaller.
+//
+// Example, mock time.Now only for calls made by Expire:
+//
+//	mock.Mock(time.Now, interceptor, mock.Within(cache.Expire))
+func Within(pkgOrFunc interface{}) Option {
+	match := newFrameMatcher(pkgOrFunc)
+	return OnlyIf(func(frames []*trap.Frame) bool {
+		for _, frame := range frames {
+			if match(frame) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// MatchRecv activates the interceptor only for calls whose
+// receiver satisfies `match`, it replaces the instance
+// check of a bound method.
+//
+// Example:
+//
+//	mock.MockMethod((*Client).Do, interceptor, mock.MatchRecv(func(recv interface{}) bool {
+//		return recv.(*Client).Name == "billing"
+//	}))
+func MatchRecv(match func(recv interface{}) bool) Option {
+	if match == nil {
+		panic("match cannot be nil")
+	}
+	return func(opts *options) {
+		opts.matchRecv = match
+	}
+}
+
+func newFrameMatcher(pkgOrFunc interface{}) func(frame *trap.Frame) bool {
+	if pkg, ok := pkgOrFunc.(string); ok {
+		if pkg == "" {
+			panic("pkg cannot be empty")
+		}
+		return func(frame *trap.Frame) bool {
+			return core.MatchPkg(frame.Pkg, "", pkg)
+		}
+	}
+	v := reflect.ValueOf(pkgOrFunc)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		panic(fmt.Errorf("expect package path or func, actual: %T", pkgOrFunc))
+	}
+	fn := runtime.FuncForPC(v.Pointer())
+	if fn == nil {
+		panic(fmt.Errorf("cannot resolve func: 0x%x", v.Pointer()))
+	}
+	// method values are wrapped as `(*T).M-fm`
+	name := strings.TrimSuffix(fn.Name(), "-fm")
+	return func(frame *trap.Frame) bool {
+		return frame.Name == name || strings.HasPrefix(frame.Name, name+".")
+	}
+}
+
+func parseOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// wrap wraps interceptor to check conditions, the returned
+// recvPtr is nil if receiver is matched by MatchRecv
+func (o *options) wrap(recvPtr interface{}, interceptor Interceptor) (interface{}, Interceptor) {
+	if o.matchRecv == nil && len(o.conds) == 0 {
+		return recvPtr, interceptor
+	}
+	if o.matchRecv != nil {
+		recvPtr = nil
+	}
+	return recvPtr, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+		if o.matchRecv != nil && fn.RecvType != "" && !o.matchRecv(args.GetFieldIndex(0).Value()) {
+			return ErrCallOld
+		}
+		if len(o.conds) > 0 {
+			frames := trap.Frames()
+			for _, cond := range o.conds {
+				if !cond(frames) {
+					return ErrCallOld
+				}
+			}
+		}
+		return interceptor(ctx, fn, args, results)
+	}
+}
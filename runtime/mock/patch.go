@@ -147,38 +147,8 @@ func buildInterceptorFromPatch(recvPtr interface{}, replacer interface{}) func(c
 	// we can bypass it
 	trap.Ignore(replacer)
 
-	// first arg ctx: true => [recv,args[1:]...]
-	// first arg ctx: false => [recv, args[0:]...]
 	return func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
-		// assemble arguments
-		callArgs := make([]reflect.Value, nIn)
-		src := 0
-		dst := 0
-
-		if fn.RecvType != "" {
-			if recvPtr != nil {
-				// patching an instance method
-				src++
-				// replacer's does not have receiver
-			} else {
-				// set receiver
-				if nIn > 0 {
-					callArgs[dst] = reflect.ValueOf(args.GetFieldIndex(0).Ptr()).Elem()
-					dst++
-					src++
-				}
-			}
-		}
-		if fn.FirstArgCtx {
-			callArgs[dst] = reflect.ValueOf(ctx)
-			dst++
-		}
-		for i := 0; i < nIn-dst; i++ {
-			// fail if with the following setup:
-			//    reflect: Call using zero Value argument
-			// callArgs[dst+i] = reflect.ValueOf(args.GetFieldIndex(src + i).Value())
-			callArgs[dst+i] = reflect.ValueOf(args.GetFieldIndex(src + i).Ptr()).Elem()
-		}
+		callArgs := assembleCallArgs(ctx, fn, recvPtr, args, nIn)
 
 		// call the function
 		var res []reflect.Value
@@ -188,21 +158,62 @@ func buildInterceptorFromPatch(recvPtr interface{}, replacer interface{}) func(c
 			res = v.CallSlice(callArgs)
 		}
 
-		// assign result
-		nOut := len(res)
-		resLen := nOut
-		if fn.LastResultErr {
-			resLen--
-		}
-		for i := 0; i < resLen; i++ {
-			results.GetFieldIndex(i).Set(res[i].Interface())
-		}
+		setCallResults(fn, results, res)
+		return nil
+	}
+}
+
+// assembleCallArgs converts intercepted args to arguments
+// of a function with `nIn` params:
+//
+//	first arg ctx: true => [recv,args[1:]...]
+//	first arg ctx: false => [recv, args[0:]...]
+func assembleCallArgs(ctx context.Context, fn *core.FuncInfo, recvPtr interface{}, args core.Object, nIn int) []reflect.Value {
+	callArgs := make([]reflect.Value, nIn)
+	src := 0
+	dst := 0
 
-		if fn.LastResultErr {
-			results.(core.ObjectWithErr).GetErr().Set(res[nOut-1].Interface())
+	if fn.RecvType != "" {
+		if recvPtr != nil {
+			// patching an instance method
+			src++
+			// replacer's does not have receiver
+		} else {
+			// set receiver
+			if nIn > 0 {
+				callArgs[dst] = reflect.ValueOf(args.GetFieldIndex(0).Ptr()).Elem()
+				dst++
+				src++
+			}
 		}
+	}
+	if fn.FirstArgCtx {
+		callArgs[dst] = reflect.ValueOf(ctx)
+		dst++
+	}
+	for i := 0; i < nIn-dst; i++ {
+		// fail if with the following setup:
+		//    reflect: Call using zero Value argument
+		// callArgs[dst+i] = reflect.ValueOf(args.GetFieldIndex(src + i).Value())
+		callArgs[dst+i] = reflect.ValueOf(args.GetFieldIndex(src + i).Ptr()).Elem()
+	}
+	return callArgs
+}
 
-		return nil
+// setCallResults assigns `res` to intercepted results,
+// the last one is treated as error if fn.LastResultErr
+func setCallResults(fn *core.FuncInfo, results core.Object, res []reflect.Value) {
+	nOut := len(res)
+	resLen := nOut
+	if fn.LastResultErr {
+		resLen--
+	}
+	for i := 0; i < resLen; i++ {
+		results.GetFieldIndex(i).Set(res[i].Interface())
+	}
+
+	if fn.LastResultErr {
+		results.(core.ObjectWithErr).GetErr().Set(res[nOut-1].Interface())
 	}
 }
 
@@ -0,0 +1,51 @@
+package mock
+
+import (
+	"fmt"
+
+	"github.com/xhd2015/xgo/runtime/core"
+	"github.com/xhd2015/xgo/runtime/functab"
+)
+
+// MockPattern setup mock on all functions and methods whose
+// package matches `pkgPattern` and identity name matches
+// `funcPattern`.
+//
+// `pkgPattern` is a package path, `path/...` to include
+// all sub packages, or `*suffix` of package path.
+// `funcPattern` is a glob over identity names like `Func`,
+// `Type.Method` and `(*Type).Method`, where '*' matches any
+// sequence of characters and '?' matches a single character,
+// "(*" is treated literally.
+//
+// The pattern language is the same as the compile time
+// patterns used by xgo flags, see core.MatchPattern, except
+// that package names are not known at runtime, so `pkgPattern`
+// cannot be a package name.
+//
+// Example:
+//
+//	mock.MockPattern("github.com/acme/billing/client", "(*Client).*", interceptor)
+//
+// Like MockByName on a method, the receiver is passed
+// to the interceptor as the first arg.
+func MockPattern(pkgPattern string, funcPattern string, interceptor Interceptor) func() {
+	var cancels []func()
+	for _, fn := range functab.GetFuncs() {
+		if fn.Kind != core.Kind_Func || fn.Interface || fn.Closure || fn.IdentityName == "" {
+			continue
+		}
+		if !core.MatchPkg(fn.Pkg, "", pkgPattern) || !core.MatchGlob(fn.IdentityName, funcPattern) {
+			continue
+		}
+		cancels = append(cancels, mock(nil, fn, 0, 0, interceptor))
+	}
+	if len(cancels) == 0 {
+		panic(fmt.Errorf("failed to setup mock for: %s %s, no function matched", pkgPattern, funcPattern))
+	}
+	return func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/xhd2015/xgo/runtime/core"
+	"github.com/xhd2015/xgo/runtime/trap"
+)
+
+// Call is a recorded call of a spied function
+type Call struct {
+	// Args are arguments in the same order of the
+	// function's signature, including ctx
+	Args []interface{}
+
+	// Results are results in the same order of the
+	// function's signature, including error
+	Results []interface{}
+
+	// Err is the last result if it is an error
+	Err error
+
+	// Panic is the panicked value, if any
+	Panic interface{}
+
+	// Goroutine identifies the goroutine making the call
+	Goroutine uintptr
+
+	Begin    time.Time
+	Duration time.Duration
+}
+
+// SpyLog records calls of a function without
+// altering its behavior
+type SpyLog struct {
+	fnType  reflect.Type
+	recvPtr interface{}
+	cancel  func()
+
+	mutex sync.Mutex
+	calls []*Call
+}
+
+// Spy records calls to `fn`, `fn` can be a function
+// or a method. Like Mock, if `fn` is a method, only
+// the bound instance will be recorded.
+// The scope is the same as Mock.
+func Spy(fn interface{}) *SpyLog {
+	if fn == nil {
+		panic("fn cannot be nil")
+	}
+	fnType := reflect.TypeOf(fn)
+	if fnType.Kind() != reflect.Func {
+		panic(fmt.Errorf("fn should be func, actual: %T", fn))
+	}
+	recvPtr, fnInfo, funcPC, trappingPC := getFunc(fn)
+	s := &SpyLog{
+		fnType:  fnType,
+		recvPtr: recvPtr,
+	}
+	match := newCallMatcher(recvPtr, fnInfo, funcPC, trappingPC)
+	s.cancel = trap.AddFuncInfoInterceptor(fnInfo, &trap.Interceptor{
+		Pre: func(ctx context.Context, f *core.FuncInfo, args, result core.Object) (interface{}, error) {
+			if !match(f, args) {
+				return nil, trap.ErrSkip
+			}
+			callArgs := assembleCallArgs(ctx, f, recvPtr, args, fnType.NumIn())
+			argValues := make([]interface{}, len(callArgs))
+			for i, arg := range callArgs {
+				argValues[i] = arg.Interface()
+			}
+			return &Call{
+				Args:      argValues,
+				Goroutine: uintptr(__xgo_link_getcurg()),
+				Begin:     getNow(),
+			}, nil
+		},
+		Post: func(ctx context.Context, f *core.FuncInfo, args, result core.Object, data interface{}) error {
+			call := data.(*Call)
+			call.Duration = getNow().Sub(call.Begin)
+			call.Panic = __xgo_link_peek_panic()
+
+			n := result.NumField()
+			for i := 0; i < n; i++ {
+				call.Results = append(call.Results, result.GetFieldIndex(i).Value())
+			}
+			if f.LastResultErr {
+				errValue := result.(core.ObjectWithErr).GetErr().Value()
+				call.Results = append(call.Results, errValue)
+				if errValue != nil {
+					call.Err = errValue.(error)
+				}
+			}
+
+			s.mutex.Lock()
+			s.calls = append(s.calls, call)
+			s.mutex.Unlock()
+			return nil
+		},
+	})
+	return s
+}
+
+// Calls returns all recorded calls in order
+func (c *SpyLog) Calls() []*Call {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	calls := make([]*Call, len(c.calls))
+	copy(calls, c.calls)
+	return calls
+}
+
+// LastCall returns the last recorded call,
+// or nil if there is none
+func (c *SpyLog) LastCall() *Call {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.calls) == 0 {
+		return nil
+	}
+	return c.calls[len(c.calls)-1]
+}
+
+// Count returns number of recorded calls
+func (c *SpyLog) Count() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.calls)
+}
+
+// CalledWith checks if any recorded call's arguments
+// match `args`, a plain value is treated as Eq(value)
+func (c *SpyLog) CalledWith(args ...interface{}) bool {
+	matchers := toMatchers(c.fnType, args)
+	for _, call := range c.Calls() {
+		matched := true
+		for i, m := range matchers {
+			if !m.Match(call.Args[i]) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// Cancel stops recording
+func (c *SpyLog) Cancel() {
+	c.cancel()
+}
+
+func getNow() (now time.Time) {
+	trap.Direct(func() {
+		now = time.Now()
+	})
+	return
+}
@@ -0,0 +1,285 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/xhd2015/xgo/runtime/core"
+)
+
+// Stub is a typed builder of mock rules for a function.
+// When a call matches no rule, the original function
+// is called.
+//
+// Example:
+//
+//	mock.On(Fetch).
+//		When(mock.Eq("id1"), mock.Any()).Return(user, nil).Times(2).
+//		Then().Return(nil, err)
+type Stub struct {
+	fnType  reflect.Type
+	recvPtr interface{}
+	fnInfo  *core.FuncInfo
+	cancel  func()
+
+	mutex sync.Mutex
+	rules []*Rule
+}
+
+// Rule is a list of sequenced returns applied when
+// all matchers match the call arguments.
+type Rule struct {
+	stub     *Stub
+	matchers []Matcher
+	steps    []*returnStep
+}
+
+type returnStep struct {
+	results []reflect.Value
+	times   int // -1: not set
+	count   int
+}
+
+// On setup a mock on `fn` in the same way as Mock does,
+// the behavior is defined by rules added later.
+// `fn` must be a function or a method.
+func On(fn interface{}) *Stub {
+	if fn == nil {
+		panic("fn cannot be nil")
+	}
+	fnType := reflect.TypeOf(fn)
+	if fnType.Kind() != reflect.Func {
+		panic(fmt.Errorf("fn should be func, actual: %T", fn))
+	}
+	recvPtr, fnInfo, funcPC, trappingPC := getFunc(fn)
+	s := &Stub{
+		fnType:  fnType,
+		recvPtr: recvPtr,
+		fnInfo:  fnInfo,
+	}
+	s.cancel = mock(recvPtr, fnInfo, funcPC, trappingPC, s.intercept)
+	return s
+}
+
+// When adds a rule that applies only when each argument
+// matches the corresponding matcher. A plain value is
+// treated as Eq(value).
+func (c *Stub) When(matchers ...interface{}) *Rule {
+	return c.addRule(toMatchers(c.fnType, matchers))
+}
+
+// Return adds a rule that matches all arguments
+func (c *Stub) Return(results ...interface{}) *Rule {
+	return c.addRule(nil).Return(results...)
+}
+
+// Cancel removes the mock
+func (c *Stub) Cancel() {
+	c.cancel()
+}
+
+func (c *Stub) addRule(matchers []Matcher) *Rule {
+	rule := &Rule{
+		stub:     c,
+		matchers: matchers,
+	}
+	c.mutex.Lock()
+	c.rules = append(c.rules, rule)
+	c.mutex.Unlock()
+	return rule
+}
+
+// Return sets results of current step, results are
+// checked against the function's signature.
+func (c *Rule) Return(results ...interface{}) *Rule {
+	fnType := c.stub.fnType
+	values, wantType, actualType, match := checkResultsMatch(fnType, results)
+	if !match {
+		panic(fmt.Errorf("results should have type: %s, actual: %s", wantType, actualType))
+	}
+
+	c.stub.mutex.Lock()
+	defer c.stub.mutex.Unlock()
+	n := len(c.steps)
+	if n > 0 && c.steps[n-1].results == nil {
+		c.steps[n-1].results = values
+		return c
+	}
+	if n > 0 {
+		panic("Return already called, use Then() to add sequenced returns")
+	}
+	c.steps = append(c.steps, &returnStep{
+		results: values,
+		times:   -1,
+	})
+	return c
+}
+
+// Times limits current step to be applied n times,
+// after that the next step will be applied. If there
+// is no next step, the rule no longer matches.
+func (c *Rule) Times(n int) *Rule {
+	if n <= 0 {
+		panic(fmt.Errorf("times should be positive: %d", n))
+	}
+	c.stub.mutex.Lock()
+	defer c.stub.mutex.Unlock()
+	if len(c.steps) == 0 || c.steps[len(c.steps)-1].results == nil {
+		panic("Times must be called after Return")
+	}
+	c.steps[len(c.steps)-1].times = n
+	return c
+}
+
+// Then starts a new step, whose results should be
+// set by Return.
+// If a step before Then has no Times set, it will be
+// applied only once.
+func (c *Rule) Then() *Rule {
+	c.stub.mutex.Lock()
+	defer c.stub.mutex.Unlock()
+	if len(c.steps) == 0 || c.steps[len(c.steps)-1].results == nil {
+		panic("Then must be called after Return")
+	}
+	c.steps = append(c.steps, &returnStep{
+		times: -1,
+	})
+	return c
+}
+
+func (c *Rule) match(args []reflect.Value) bool {
+	for i, m := range c.matchers {
+		if !m.Match(args[i].Interface()) {
+			return false
+		}
+	}
+	return true
+}
+
+// toMatchers converts each arg to a Matcher,
+// plain values are converted by Eq
+func toMatchers(fnType reflect.Type, matchers []interface{}) []Matcher {
+	nIn := fnType.NumIn()
+	if len(matchers) != nIn {
+		panic(fmt.Errorf("matchers should have %d args: %s, actual: %d", nIn, formatFuncType(fnType, false), len(matchers)))
+	}
+	ms := make([]Matcher, nIn)
+	for i, m := range matchers {
+		if matcher, ok := m.(Matcher); ok {
+			ms[i] = matcher
+		} else {
+			ms[i] = Eq(m)
+		}
+	}
+	return ms
+}
+
+// nextResults must be called with stub's mutex held
+func (c *Rule) nextResults() []reflect.Value {
+	n := len(c.steps)
+	for i, step := range c.steps {
+		if step.results == nil {
+			// missing Return after Then
+			break
+		}
+		times := step.times
+		if times < 0 && i < n-1 && c.steps[i+1].results != nil {
+			times = 1
+		}
+		if times >= 0 && step.count >= times {
+			continue
+		}
+		step.count++
+		return step.results
+	}
+	return nil
+}
+
+func (c *Stub) intercept(ctx context.Context, fn *core.FuncInfo, args core.Object, results core.Object) error {
+	callArgs := assembleCallArgs(ctx, fn, c.recvPtr, args, c.fnType.NumIn())
+
+	c.mutex.Lock()
+	var res []reflect.Value
+	for _, rule := range c.rules {
+		if !rule.match(callArgs) {
+			continue
+		}
+		res = rule.nextResults()
+		if res != nil {
+			break
+		}
+	}
+	c.mutex.Unlock()
+
+	if res == nil {
+		return ErrCallOld
+	}
+	setCallResults(fn, results, res)
+	return nil
+}
+
+// checkResultsMatch checks results can be returned by function type `f`,
+// nil can be used for pointer, interface, slice, map, chan and func.
+func checkResultsMatch(f reflect.Type, results []interface{}) (values []reflect.Value, wantType string, actualType string, match bool) {
+	nOut := f.NumOut()
+	values = make([]reflect.Value, len(results))
+	match = len(results) == nOut
+	for i, res := range results {
+		if res == nil {
+			if i < nOut && isNillableKind(f.Out(i).Kind()) {
+				values[i] = reflect.Zero(f.Out(i))
+			} else {
+				match = false
+			}
+			continue
+		}
+		v := reflect.ValueOf(res)
+		if i < nOut && v.Type().AssignableTo(f.Out(i)) {
+			if v.Type() != f.Out(i) {
+				// i.e. *MyError -> error
+				converted := reflect.New(f.Out(i)).Elem()
+				converted.Set(v)
+				v = converted
+			}
+			values[i] = v
+			continue
+		}
+		match = false
+	}
+	if match {
+		return values, "", "", true
+	}
+	return nil, formatResultTypes(f), formatValueTypes(results), false
+}
+
+func isNillableKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map, reflect.Chan, reflect.Func:
+		return true
+	}
+	return false
+}
+
+func formatResultTypes(f reflect.Type) string {
+	nout := f.NumOut()
+	types := make([]string, nout)
+	for i := 0; i < nout; i++ {
+		types[i] = f.Out(i).String()
+	}
+	return "(" + strings.Join(types, ",") + ")"
+}
+
+func formatValueTypes(values []interface{}) string {
+	types := make([]string, len(values))
+	for i, v := range values {
+		if v == nil {
+			types[i] = "nil"
+			continue
+		}
+		types[i] = reflect.TypeOf(v).String()
+	}
+	return "(" + strings.Join(types, ",") + ")"
+}
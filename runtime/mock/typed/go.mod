@@ -0,0 +1,7 @@
+module github.com/xhd2015/xgo/runtime/mock/typed
+
+go 1.18
+
+require github.com/xhd2015/xgo/runtime v1.0.25
+
+replace github.com/xhd2015/xgo/runtime => ../../
@@ -0,0 +1,13 @@
+package typed
+
+import "github.com/xhd2015/xgo/runtime/mock"
+
+// MockInterface setup mock on `method` of every concrete
+// type that implements interface I, see mock.MockInterface.
+//
+// Example:
+//
+//	typed.MockInterface[Store]("Save", interceptor)
+func MockInterface[I any](method string, interceptor mock.Interceptor) func() {
+	return mock.MockInterface((*I)(nil), method, interceptor)
+}
@@ -0,0 +1,14 @@
+package typed
+
+import "github.com/xhd2015/xgo/runtime/mock"
+
+// MockType setup mock on `method` of all receivers of
+// type T, see mock.MockType.
+//
+// Example:
+//
+//	typed.MockType[*Client]("Do", interceptor)
+func MockType[T any](method string, interceptor mock.Interceptor, opts ...mock.Option) func() {
+	var v T
+	return mock.MockType(v, method, interceptor, opts...)
+}
@@ -0,0 +1,21 @@
+// Package typed provides type safe versions of mock APIs
+// using generics. It is a separate module declaring go1.18,
+// because github.com/xhd2015/xgo/runtime targets go1.14
+// and cannot use generics itself.
+package typed
+
+import "github.com/xhd2015/xgo/runtime/mock"
+
+// PatchFunc is the type safe version of mock.Patch for
+// functions and methods, mismatched replacer is
+// rejected by the compiler.
+func PatchFunc[F any](fn F, replacer F) func() {
+	return mock.Patch(fn, replacer)
+}
+
+// PatchVar is the type safe version of mock.Patch for
+// variables, the variable pointed by `ptr` reads
+// the value returned by `getter`.
+func PatchVar[T any](ptr *T, getter func() T) func() {
+	return mock.Patch(ptr, getter)
+}
@@ -0,0 +1,151 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/xhd2015/xgo/runtime/core"
+	"github.com/xhd2015/xgo/runtime/trap"
+)
+
+// Invocation is an intercepted call passed to the
+// wrapper of Wrap
+type Invocation struct {
+	Func *core.FuncInfo
+
+	// Args are arguments in the same order of
+	// `fn`'s signature passed to Wrap, including ctx
+	Args []interface{}
+
+	// Results are returned to the caller after the
+	// wrapper returns, including error.
+	// It is set by CallOld, and can be modified.
+	Results []interface{}
+
+	fnType reflect.Type
+	recv   reflect.Value // receiver of bound method
+}
+
+// Wrap setup mock on `fn` in the same way as Mock does,
+// `wrapper` can call the original function any times
+// with the same or rewritten arguments, and
+// post-process results.
+//
+// Example, retry on error:
+//
+//	mock.Wrap(fetch, func(inv *mock.Invocation) {
+//		for i := 0; i < 3; i++ {
+//			inv.CallOld()
+//			if inv.Err() == nil {
+//				return
+//			}
+//		}
+//	})
+func Wrap(fn interface{}, wrapper func(inv *Invocation)) func() {
+	if fn == nil {
+		panic("fn cannot be nil")
+	}
+	if wrapper == nil {
+		panic("wrapper cannot be nil")
+	}
+	fnType := reflect.TypeOf(fn)
+	if fnType.Kind() != reflect.Func {
+		panic(fmt.Errorf("fn should be func, actual: %T", fn))
+	}
+	recvPtr, fnInfo, funcPC, trappingPC := getFunc(fn)
+	if fnInfo.Func == nil {
+		panic(fmt.Errorf("cannot wrap %s: original function not available", fnInfo.DisplayName()))
+	}
+	trap.Ignore(wrapper)
+
+	return mock(recvPtr, fnInfo, funcPC, trappingPC, func(ctx context.Context, f *core.FuncInfo, args, results core.Object) error {
+		callArgs := assembleCallArgs(ctx, f, recvPtr, args, fnType.NumIn())
+		inv := &Invocation{
+			Func:   f,
+			Args:   make([]interface{}, len(callArgs)),
+			fnType: fnType,
+		}
+		for i, arg := range callArgs {
+			inv.Args[i] = arg.Interface()
+		}
+		if f.RecvType != "" && recvPtr != nil {
+			inv.recv = reflect.ValueOf(args.GetFieldIndex(0).Ptr()).Elem()
+		}
+
+		wrapper(inv)
+
+		if inv.Results == nil && fnType.NumOut() > 0 {
+			// not called, return zero values
+			return nil
+		}
+		values, wantType, actualType, match := checkResultsMatch(fnType, inv.Results)
+		if !match {
+			panic(fmt.Errorf("results should have type: %s, actual: %s", wantType, actualType))
+		}
+		setCallResults(f, results, values)
+		return nil
+	})
+}
+
+// CallOld calls the original function with Args,
+// and stores its results to Results.
+func (c *Invocation) CallOld() {
+	c.Results = c.Call(c.Args...)
+}
+
+// Call calls the original function with `args`, which
+// should match `fn`'s signature passed to Wrap,
+// and returns its results. Results is not changed.
+func (c *Invocation) Call(args ...interface{}) []interface{} {
+	nIn := c.fnType.NumIn()
+	if len(args) != nIn {
+		panic(fmt.Errorf("args should have %d args: %s, actual: %d", nIn, formatFuncType(c.fnType, false), len(args)))
+	}
+	callArgs := make([]reflect.Value, 0, nIn+1)
+	if c.recv.IsValid() {
+		callArgs = append(callArgs, c.recv)
+	}
+	for i, arg := range args {
+		argType := c.fnType.In(i)
+		if arg == nil {
+			callArgs = append(callArgs, reflect.Zero(argType))
+			continue
+		}
+		v := reflect.ValueOf(arg)
+		if !v.Type().AssignableTo(argType) {
+			panic(fmt.Errorf("arg %d should have type: %s, actual: %T", i, argType, arg))
+		}
+		if v.Type() != argType {
+			converted := reflect.New(argType).Elem()
+			converted.Set(v)
+			v = converted
+		}
+		callArgs = append(callArgs, v)
+	}
+
+	orig := reflect.ValueOf(c.Func.Func)
+	var res []reflect.Value
+	trap.CallOriginal(c.Func, func() {
+		if c.fnType.IsVariadic() {
+			res = orig.CallSlice(callArgs)
+		} else {
+			res = orig.Call(callArgs)
+		}
+	})
+	results := make([]interface{}, len(res))
+	for i, r := range res {
+		results[i] = r.Interface()
+	}
+	return results
+}
+
+// Err returns the last result if it is a non-nil error
+func (c *Invocation) Err() error {
+	n := len(c.Results)
+	if n == 0 || !c.Func.LastResultErr {
+		return nil
+	}
+	err, _ := c.Results[n-1].(error)
+	return err
+}
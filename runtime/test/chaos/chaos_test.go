@@ -0,0 +1,102 @@
+package chaos
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xhd2015/xgo/runtime/chaos"
+	"github.com/xhd2015/xgo/runtime/mock"
+)
+
+const pkg = "github.com/xhd2015/xgo/runtime/test/chaos"
+
+type Store struct{}
+
+func (c *Store) Load(key string) (string, error) {
+	return "value:" + key, nil
+}
+
+func Compute(n int) int {
+	return n * 2
+}
+
+func TestDisabledByDefault(t *testing.T) {
+	mock.Unsetenv(chaos.XGO_CHAOS)
+	chaos.Enable(t, &chaos.Rule{Pkg: pkg, Func: "(*Store).*", ErrorRate: 1})
+
+	_, err := (&Store{}).Load("a")
+	if err != nil {
+		t.Fatalf("expect no error when chaos disabled, actual: %v", err)
+	}
+}
+
+func TestInjectError(t *testing.T) {
+	mock.Setenv(chaos.XGO_CHAOS, "1")
+	errDown := errors.New("store down")
+	chaos.Enable(t, &chaos.Rule{Pkg: pkg, Func: "(*Store).*", ErrorRate: 1, Error: errDown})
+
+	res, err := (&Store{}).Load("a")
+	if err != errDown {
+		t.Fatalf("expect injected error, actual: %v", err)
+	}
+	if res != "" {
+		t.Fatalf("expect zero result, actual: %q", res)
+	}
+	// no error result, not affected
+	if n := Compute(1); n != 2 {
+		t.Fatalf("expect Compute not affected, actual: %d", n)
+	}
+}
+
+func TestInjectPanic(t *testing.T) {
+	mock.Setenv(chaos.XGO_CHAOS, "1")
+	chaos.Enable(t, &chaos.Rule{Pkg: pkg, Func: "Compute", PanicRate: 1})
+
+	var pe interface{}
+	func() {
+		defer func() {
+			pe = recover()
+		}()
+		Compute(1)
+	}()
+	if _, ok := pe.(*chaos.Panic); !ok {
+		t.Fatalf("expect injected panic, actual: %v", pe)
+	}
+}
+
+func TestInjectLatency(t *testing.T) {
+	mock.Setenv(chaos.XGO_CHAOS, "1")
+	chaos.Enable(t, &chaos.Rule{Pkg: pkg, Func: "Compute", LatencyRate: 1, Latency: 50 * time.Millisecond})
+
+	begin := time.Now()
+	if n := Compute(1); n != 2 {
+		t.Fatalf("expect result not affected, actual: %d", n)
+	}
+	if cost := time.Since(begin); cost < 50*time.Millisecond {
+		t.Fatalf("expect latency injected, actual cost: %v", cost)
+	}
+}
+
+func TestSeedReproducible(t *testing.T) {
+	mock.Setenv(chaos.XGO_CHAOS, "1")
+	mock.Setenv(chaos.XGO_CHAOS_SEED, "42")
+
+	run := func() []bool {
+		cancel := chaos.Enable(t, &chaos.Rule{Pkg: pkg, Func: "(*Store).Load", ErrorRate: 0.5})
+		defer cancel()
+		var failed []bool
+		for i := 0; i < 20; i++ {
+			_, err := (&Store{}).Load("a")
+			failed = append(failed, err != nil)
+		}
+		return failed
+	}
+	a := run()
+	b := run()
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("expect same faults with same seed, differ at %d", i)
+		}
+	}
+}
@@ -3,7 +3,10 @@ module github.com/xhd2015/xgo/runtime/test
 go 1.18
 
 require (
-	github.com/xhd2015/xgo/runtime v1.0.16
+	github.com/xhd2015/xgo/runtime v1.0.25
+	github.com/xhd2015/xgo/runtime/mock/typed v1.0.25
 )
 
 replace github.com/xhd2015/xgo/runtime => ../
+
+replace github.com/xhd2015/xgo/runtime/mock/typed => ../mock/typed
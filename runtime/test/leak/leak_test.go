@@ -0,0 +1,104 @@
+package leak
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xhd2015/xgo/runtime/leak"
+)
+
+func worker(stop chan struct{}) {
+	<-stop
+}
+
+func startWorker(stop chan struct{}) {
+	go worker(stop)
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestLeaks -v ./test/leak
+func TestLeaks(t *testing.T) {
+	stop := make(chan struct{})
+	startWorker(stop)
+
+	leaks := leak.Leaks(t)
+	if len(leaks) != 1 {
+		t.Fatalf("expect 1 leak, actual: %d", len(leaks))
+	}
+	g := leaks[0]
+	pkg := "github.com/xhd2015/xgo/runtime/test/leak"
+	if g.Func != pkg+".worker" {
+		t.Fatalf("expect func to be worker, actual: %s", g.Func)
+	}
+	if g.Creator != pkg+".startWorker" || !strings.HasSuffix(g.File, "leak_test.go") {
+		t.Fatalf("expect created by startWorker, actual: %s at %s:%d", g.Creator, g.File, g.Line)
+	}
+	if g.Test != "TestLeaks" {
+		t.Fatalf("expect test to be TestLeaks, actual: %s", g.Test)
+	}
+
+	close(stop)
+	waitNoLeaks(t)
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestLeaksSubtest -v ./test/leak
+func TestLeaksSubtest(t *testing.T) {
+	stop := make(chan struct{})
+	t.Run("sub", func(t *testing.T) {
+		go func() {
+			startWorker(stop)
+		}()
+	})
+
+	var leaks []*leak.Goroutine
+	deadline := time.Now().Add(time.Second)
+	for len(leaks) < 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		leaks = workers(leak.Leaks(t))
+	}
+	if len(leaks) != 1 {
+		t.Fatalf("expect 1 leaked worker from subtest, actual: %d", len(leaks))
+	}
+	g := leaks[0]
+	if g.Test != "TestLeaksSubtest/sub" {
+		t.Fatalf("expect test to be TestLeaksSubtest/sub, actual: %s", g.Test)
+	}
+	if g.Parent == nil || !strings.HasPrefix(g.Parent.Creator, "github.com/xhd2015/xgo/runtime/test/leak.TestLeaksSubtest.func") {
+		t.Fatalf("expect worker to be created by a goroutine of the subtest, actual: %s", leak.Format(leaks))
+	}
+
+	close(stop)
+	waitNoLeaks(t)
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestCheck -v ./test/leak
+func TestCheck(t *testing.T) {
+	leak.Check(t)
+
+	stop := make(chan struct{})
+	startWorker(stop)
+	// exits shortly after the test
+	t.Cleanup(func() {
+		close(stop)
+	})
+}
+
+func workers(list []*leak.Goroutine) []*leak.Goroutine {
+	var res []*leak.Goroutine
+	for _, g := range list {
+		if strings.HasSuffix(g.Func, ".worker") {
+			res = append(res, g)
+		}
+	}
+	return res
+}
+
+func waitNoLeaks(t *testing.T) {
+	deadline := time.Now().Add(time.Second)
+	for len(leak.Leaks(t)) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expect no leaks after stopped, actual: %s", leak.Format(leak.Leaks(t)))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,153 @@
+package mock_caller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/core"
+	"github.com/xhd2015/xgo/runtime/mock"
+	"github.com/xhd2015/xgo/runtime/trap"
+
+	util "github.com/xhd2015/xgo/runtime/test/mock_caller/util.v2"
+)
+
+func load() string {
+	return "real"
+}
+
+func direct() string {
+	return load()
+}
+
+func nested() string {
+	return direct()
+}
+
+func other() string {
+	return load()
+}
+
+func stub(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+	results.GetFieldIndex(0).Set("mock")
+	return nil
+}
+
+func TestFromCaller(t *testing.T) {
+	mock.Mock(load, stub, mock.FromCaller(direct))
+
+	if s := direct(); s != "mock" {
+		t.Fatalf("expect direct() to be %q, actual: %q", "mock", s)
+	}
+	if s := other(); s != "real" {
+		t.Fatalf("expect other() to be %q, actual: %q", "real", s)
+	}
+	if s := load(); s != "real" {
+		t.Fatalf("expect load() to be %q, actual: %q", "real", s)
+	}
+}
+
+func TestFromCallerPkg(t *testing.T) {
+	mock.Mock(load, stub, mock.FromCaller("github.com/xhd2015/xgo/runtime/test/mock_caller"))
+
+	if s := other(); s != "mock" {
+		t.Fatalf("expect other() to be %q, actual: %q", "mock", s)
+	}
+}
+
+// dots in the last path element are escaped in
+// symbol names, i.e. util%2ev2
+func TestFromCallerDottedPkg(t *testing.T) {
+	mock.Mock(load, stub, mock.FromCaller("github.com/xhd2015/xgo/runtime/test/mock_caller/util.v2"))
+
+	if s := util.Call(load); s != "mock" {
+		t.Fatalf("expect util.Call(load) to be %q, actual: %q", "mock", s)
+	}
+	if s := direct(); s != "real" {
+		t.Fatalf("expect direct() to be %q, actual: %q", "real", s)
+	}
+}
+
+func TestOnlyIf(t *testing.T) {
+	mock.Mock(load, stub, mock.OnlyIf(func(frames []*trap.Frame) bool {
+		return len(frames) >= 2 && frames[1].FuncInfo != nil && frames[1].FuncInfo.Name == "nested"
+	}))
+
+	if s := nested(); s != "mock" {
+		t.Fatalf("expect nested() to be %q, actual: %q", "mock", s)
+	}
+	if s := direct(); s != "real" {
+		t.Fatalf("expect direct() to be %q, actual: %q", "real", s)
+	}
+}
+
+func TestWithin(t *testing.T) {
+	mock.Mock(load, stub, mock.Within(nested))
+
+	if s := nested(); s != "mock" {
+		t.Fatalf("expect nested() to be %q, actual: %q", "mock", s)
+	}
+	if s := direct(); s != "real" {
+		t.Fatalf("expect direct() to be %q, actual: %q", "real", s)
+	}
+}
+
+func TestWithinClosure(t *testing.T) {
+	mock.Mock(load, stub, mock.Within(TestWithinClosure))
+
+	run := func() string {
+		return other()
+	}
+	if s := run(); s != "mock" {
+		t.Fatalf("expect run() to be %q, actual: %q", "mock", s)
+	}
+}
+
+func TestCaller(t *testing.T) {
+	t.Cleanup(trap.TrackFrames())
+
+	var caller *trap.Frame
+	var frames []*trap.Frame
+	mock.Mock(load, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+		caller = trap.Caller()
+		frames = trap.Frames()
+		return mock.ErrCallOld
+	})
+	nested()
+
+	pkg := "github.com/xhd2015/xgo/runtime/test/mock_caller"
+	if caller == nil || caller.Name != pkg+".direct" {
+		t.Fatalf("expect caller to be direct, actual: %+v", caller)
+	}
+	if caller.Pkg != pkg {
+		t.Fatalf("expect caller pkg %q, actual: %q", pkg, caller.Pkg)
+	}
+	// TestCaller started before TrackFrames, so it is not recorded
+	if len(frames) != 2 || frames[1].Name != pkg+".nested" {
+		t.Fatalf("expect frames: direct, nested, actual: %v", frameNames(frames))
+	}
+}
+
+func TestCallerOutsideInterceptor(t *testing.T) {
+	t.Cleanup(trap.TrackFrames())
+
+	caller := callGetCaller()
+	if caller == nil || caller.Name != "github.com/xhd2015/xgo/runtime/test/mock_caller.callGetCaller" {
+		t.Fatalf("expect caller to be callGetCaller, actual: %+v", caller)
+	}
+}
+
+func callGetCaller() *trap.Frame {
+	return getCaller()
+}
+
+func getCaller() *trap.Frame {
+	return trap.Caller()
+}
+
+func frameNames(frames []*trap.Frame) []string {
+	names := make([]string, len(frames))
+	for i, frame := range frames {
+		names[i] = frame.Name
+	}
+	return names
+}
@@ -0,0 +1,5 @@
+package util
+
+func Call(fn func() string) string {
+	return fn()
+}
@@ -0,0 +1,122 @@
+package mock_cassette
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/mock"
+)
+
+type Weather struct {
+	City string
+	Temp int
+}
+
+var realCalls int
+
+func getWeather(ctx context.Context, city string) (*Weather, error) {
+	realCalls++
+	if city == "" {
+		return nil, errors.New("empty city")
+	}
+	if city == "Atlantis" {
+		panic("city not found: " + city)
+	}
+	return &Weather{City: city, Temp: len(city)}, nil
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cassette.json")
+
+	stop := mock.Record(getWeather, file)
+	w, err := getWeather(context.Background(), "Paris")
+	if err != nil || w.Temp != 5 {
+		t.Fatalf("expect real call while recording, actual: %+v %v", w, err)
+	}
+	_, err = getWeather(context.Background(), "")
+	if err == nil {
+		t.Fatalf("expect error while recording")
+	}
+	stop()
+
+	realCalls = 0
+	mock.Replay(getWeather, file)
+	w, err = getWeather(context.TODO(), "Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.City != "Paris" || w.Temp != 5 {
+		t.Fatalf("expect replayed weather, actual: %+v", w)
+	}
+	_, err = getWeather(context.TODO(), "")
+	if err == nil || err.Error() != "empty city" {
+		t.Fatalf("expect replayed error %q, actual: %v", "empty city", err)
+	}
+	if realCalls != 0 {
+		t.Fatalf("expect no real calls while replaying, actual: %d", realCalls)
+	}
+}
+
+func TestReplayNoRecordingShouldPanic(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cassette.json")
+	stop := mock.Record(getWeather, file)
+	getWeather(context.Background(), "Paris")
+	stop()
+
+	mock.Replay(getWeather, file)
+	var pe interface{}
+	func() {
+		defer func() {
+			pe = recover()
+		}()
+		getWeather(context.Background(), "London")
+	}()
+	if pe == nil {
+		t.Fatalf("expect replaying unrecorded call to panic")
+	}
+	msg := fmt.Sprint(pe)
+	if !strings.Contains(msg, `no recording with args ["London"]`) {
+		t.Fatalf("unexpected panic: %s", msg)
+	}
+}
+
+func TestReplayMissingCassetteShouldPanic(t *testing.T) {
+	var pe interface{}
+	func() {
+		defer func() {
+			pe = recover()
+		}()
+		mock.Replay(getWeather, filepath.Join(t.TempDir(), "missing.json"))
+	}()
+	if pe == nil || !strings.Contains(fmt.Sprint(pe), "XGO_MOCK_CASSETTE_MODE=record") {
+		t.Fatalf("expect missing cassette to panic with hint, actual: %v", pe)
+	}
+}
+
+func TestRecordPanicThenReplay(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cassette.json")
+	stop := mock.Record(getWeather, file)
+	func() {
+		defer func() {
+			recover()
+		}()
+		getWeather(context.Background(), "Atlantis")
+	}()
+	stop()
+
+	mock.Replay(getWeather, file)
+	var pe interface{}
+	func() {
+		defer func() {
+			pe = recover()
+		}()
+		getWeather(context.Background(), "Atlantis")
+	}()
+	if fmt.Sprint(pe) != "city not found: Atlantis" {
+		t.Fatalf("expect recorded panic replayed, actual: %v", pe)
+	}
+}
@@ -0,0 +1,139 @@
+package mock_clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/xhd2015/xgo/runtime/mock/clock"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestClockNow(t *testing.T) {
+	c := clock.Install(start)
+	defer c.Cancel()
+
+	if now := time.Now(); !now.Equal(start) {
+		t.Fatalf("expect now to be %v, actual: %v", start, now)
+	}
+	c.Advance(time.Hour)
+	if d := time.Since(start); d != time.Hour {
+		t.Fatalf("expect since to be 1h, actual: %v", d)
+	}
+	c.Set(start.Add(-time.Hour))
+	if now := time.Now(); !now.Equal(start.Add(-time.Hour)) {
+		t.Fatalf("expect clock set back, actual: %v", now)
+	}
+}
+
+func TestClockSleep(t *testing.T) {
+	c := clock.Install(start)
+	defer c.Cancel()
+
+	done := make(chan time.Time)
+	go func() {
+		time.Sleep(time.Minute)
+		done <- time.Now()
+	}()
+	c.BlockUntil(1)
+	if n := c.Sleepers(); n != 1 {
+		t.Fatalf("expect 1 sleeper, actual: %d", n)
+	}
+
+	c.Advance(30 * time.Second)
+	select {
+	case <-done:
+		t.Fatalf("expect sleeper not woken up before deadline")
+	default:
+	}
+
+	c.Advance(time.Hour)
+	woken := <-done
+	if !woken.Equal(start.Add(time.Minute)) {
+		t.Fatalf("expect woken up at deadline, actual: %v", woken)
+	}
+	if now := c.Now(); !now.Equal(start.Add(time.Hour + 30*time.Second)) {
+		t.Fatalf("expect now: %v, actual: %v", start.Add(time.Hour+30*time.Second), now)
+	}
+}
+
+func TestClockTimers(t *testing.T) {
+	c := clock.Install(start)
+	defer c.Cancel()
+
+	after := time.After(time.Second)
+	timer := time.NewTimer(2 * time.Second)
+	stopped := time.NewTimer(2 * time.Second)
+	called := make(chan bool, 1)
+	time.AfterFunc(3*time.Second, func() {
+		called <- true
+	})
+	if n := c.Pending(); n != 4 {
+		t.Fatalf("expect 4 pending, actual: %d", n)
+	}
+
+	if !stopped.Stop() {
+		t.Fatalf("expect stop active timer return true")
+	}
+	c.Advance(3 * time.Second)
+
+	if v := <-after; !v.Equal(start.Add(time.Second)) {
+		t.Fatalf("expect After fired at 1s, actual: %v", v)
+	}
+	if v := <-timer.C; !v.Equal(start.Add(2 * time.Second)) {
+		t.Fatalf("expect timer fired at 2s, actual: %v", v)
+	}
+	select {
+	case <-stopped.C:
+		t.Fatalf("expect stopped timer not fired")
+	default:
+	}
+	<-called
+
+	if timer.Reset(time.Second) {
+		t.Fatalf("expect reset fired timer return false")
+	}
+	c.Advance(time.Second)
+	if v := <-timer.C; !v.Equal(start.Add(4 * time.Second)) {
+		t.Fatalf("expect reset timer fired at 4s, actual: %v", v)
+	}
+}
+
+func TestClockTicker(t *testing.T) {
+	c := clock.Install(start)
+	defer c.Cancel()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for i := 1; i <= 3; i++ {
+		c.Advance(time.Second)
+		v := <-ticker.C
+		if !v.Equal(start.Add(time.Duration(i) * time.Second)) {
+			t.Fatalf("expect tick %d at %ds, actual: %v", i, i, v)
+		}
+	}
+
+	ticker.Stop()
+	c.Advance(time.Second)
+	select {
+	case <-ticker.C:
+		t.Fatalf("expect stopped ticker not fired")
+	default:
+	}
+}
+
+func TestClockTimerStopAfterCancel(t *testing.T) {
+	c := clock.Install(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	timer := time.NewTimer(time.Minute)
+	fnTimer := time.AfterFunc(time.Minute, func() {})
+	ticker := time.NewTicker(time.Minute)
+	c.Cancel()
+
+	// real methods must not panic
+	timer.Stop()
+	timer.Reset(time.Hour)
+	timer.Stop()
+	fnTimer.Stop()
+	ticker.Stop()
+}
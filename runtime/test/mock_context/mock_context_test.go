@@ -0,0 +1,87 @@
+package mock_context
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/core"
+	"github.com/xhd2015/xgo/runtime/mock"
+)
+
+func getUser(ctx context.Context, id string) string {
+	return "real:" + id
+}
+
+type job struct {
+	ctx  context.Context
+	id   string
+	done chan string
+}
+
+// the pool is created before any test, so workers
+// do not inherit mocks set up by tests
+var jobs = make(chan *job)
+
+func init() {
+	go func() {
+		for j := range jobs {
+			j.done <- getUser(j.ctx, j.id)
+		}
+	}()
+}
+
+func runInPool(ctx context.Context, id string) string {
+	j := &job{ctx: ctx, id: id, done: make(chan string)}
+	jobs <- j
+	return <-j.done
+}
+
+type ctxKey struct{}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestWithContextPool -v ./test/mock_context
+func TestWithContextPool(t *testing.T) {
+	ctx := mock.WithContext(context.Background(), getUser, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+		results.GetFieldIndex(0).Set("mock:" + args.GetFieldIndex(0).Value().(string))
+		return nil
+	})
+
+	if s := runInPool(ctx, "1"); s != "mock:1" {
+		t.Fatalf("expect pool call to be mocked, actual: %q", s)
+	}
+	derived, cancel := context.WithCancel(context.WithValue(ctx, ctxKey{}, "v"))
+	defer cancel()
+	if s := runInPool(derived, "2"); s != "mock:2" {
+		t.Fatalf("expect call with derived ctx to be mocked, actual: %q", s)
+	}
+	if s := runInPool(context.Background(), "3"); s != "real:3" {
+		t.Fatalf("expect call with other ctx not affected, actual: %q", s)
+	}
+	if s := getUser(context.Background(), "4"); s != "real:4" {
+		t.Fatalf("expect call with other ctx not affected, actual: %q", s)
+	}
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestWithContextNested -v ./test/mock_context
+func TestWithContextNested(t *testing.T) {
+	ctx := mock.WithContext(context.Background(), getUser, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+		results.GetFieldIndex(0).Set("outer")
+		return nil
+	})
+	inner := mock.WithContext(ctx, getUser, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+		if args.GetFieldIndex(0).Value().(string) != "inner" {
+			return mock.ErrCallOld
+		}
+		results.GetFieldIndex(0).Set("inner")
+		return nil
+	})
+
+	if s := getUser(inner, "inner"); s != "inner" {
+		t.Fatalf("expect latest mock to take effect, actual: %q", s)
+	}
+	if s := getUser(inner, "x"); s != "outer" {
+		t.Fatalf("expect falling back to outer mock, actual: %q", s)
+	}
+	if s := getUser(ctx, "inner"); s != "outer" {
+		t.Fatalf("expect parent ctx not affected by inner mock, actual: %q", s)
+	}
+}
@@ -0,0 +1,183 @@
+package mock_controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/core"
+	"github.com/xhd2015/xgo/runtime/mock"
+)
+
+func greet(s string) string {
+	return "hello " + s
+}
+
+func bye(s string) string {
+	return "bye " + s
+}
+
+// fakeT records errors instead of failing the test
+type fakeT struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (c *fakeT) Helper() {}
+func (c *fakeT) Errorf(format string, args ...interface{}) {
+	c.errors = append(c.errors, fmt.Sprintf(format, args...))
+}
+func (c *fakeT) Cleanup(f func()) {
+	c.cleanups = append(c.cleanups, f)
+}
+func (c *fakeT) finish() {
+	for i := len(c.cleanups) - 1; i >= 0; i-- {
+		c.cleanups[i]()
+	}
+}
+
+func TestControllerTimes(t *testing.T) {
+	ctrl := mock.NewController(t)
+	e := ctrl.Mock(greet, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+		results.GetFieldIndex(0).Set("mock " + args.GetFieldIndex(0).Value().(string))
+		return nil
+	}).Times(2)
+
+	res := greet("world")
+	if res != "mock world" {
+		t.Fatalf("expect greet to be mocked, actual: %q", res)
+	}
+	greet("moon")
+	if e.Count() != 2 {
+		t.Fatalf("expect count to be %d, actual: %d", 2, e.Count())
+	}
+	if n := ctrl.CallCount(greet); n != 2 {
+		t.Fatalf("expect call count to be %d, actual: %d", 2, n)
+	}
+}
+
+func TestControllerNilInterceptorCallsOld(t *testing.T) {
+	ctrl := mock.NewController(t)
+	ctrl.Mock(greet, nil).Times(1)
+
+	res := greet("world")
+	if res != "hello world" {
+		t.Fatalf("expect greet not mocked, actual: %q", res)
+	}
+}
+
+func TestControllerReportUnmet(t *testing.T) {
+	ft := &fakeT{TB: t}
+	ctrl := mock.NewController(ft)
+	ctrl.Mock(greet, nil)
+	ctrl.Mock(bye, nil).Times(2)
+	bye("world")
+	ft.finish()
+
+	if len(ft.errors) != 2 {
+		t.Fatalf("expect 2 errors, actual: %v", ft.errors)
+	}
+	if !strings.Contains(ft.errors[0], "never called") {
+		t.Fatalf("expect unused greet reported, actual: %s", ft.errors[0])
+	}
+	expectMsg := "expected to be called exactly 2 times, actual: 1"
+	if !strings.Contains(ft.errors[1], expectMsg) {
+		t.Fatalf("expect error contains %q, actual: %s", expectMsg, ft.errors[1])
+	}
+}
+
+func TestControllerNever(t *testing.T) {
+	ft := &fakeT{TB: t}
+	ctrl := mock.NewController(ft)
+	ctrl.Mock(greet, nil).Never()
+	greet("world")
+	ft.finish()
+
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "never") {
+		t.Fatalf("expect never violation reported, actual: %v", ft.errors)
+	}
+}
+
+func TestControllerInOrder(t *testing.T) {
+	ft := &fakeT{TB: t}
+	ctrl := mock.NewController(ft)
+	ctrl.InOrder(
+		ctrl.Mock(greet, nil),
+		ctrl.Mock(bye, nil),
+	)
+	bye("world")
+	greet("world")
+	ft.finish()
+
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "out of order") {
+		t.Fatalf("expect out of order reported, actual: %v", ft.errors)
+	}
+}
+
+func TestControllerInOrderAnyTimes(t *testing.T) {
+	ft := &fakeT{TB: t}
+	ctrl := mock.NewController(ft)
+	ctrl.InOrder(
+		ctrl.Mock(greet, nil).AnyTimes(),
+		ctrl.Mock(bye, nil),
+	)
+	bye("world")
+	greet("world")
+	ft.finish()
+
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "out of order") {
+		t.Fatalf("expect out of order reported, actual: %v", ft.errors)
+	}
+}
+
+func TestControllerFinishCancelsMocks(t *testing.T) {
+	ft := &fakeT{TB: t}
+	ctrl := mock.NewController(ft)
+	ctrl.Mock(greet, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+		results.GetFieldIndex(0).Set("mock")
+		return nil
+	})
+	greet("world")
+	ctrl.Finish()
+
+	res := greet("world")
+	if res != "hello world" {
+		t.Fatalf("expect mock cancelled after finish, actual: %q", res)
+	}
+	ft.finish()
+	if len(ft.errors) != 0 {
+		t.Fatalf("expect no errors, actual: %v", ft.errors)
+	}
+}
+
+func TestControllerFinishFromAnotherGoroutine(t *testing.T) {
+	ft := &fakeT{TB: t}
+	ctrl := mock.NewController(ft)
+
+	created := make(chan struct{})
+	finished := make(chan struct{})
+	res := make(chan string)
+	go func() {
+		ctrl.Mock(greet, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+			results.GetFieldIndex(0).Set("mock")
+			return nil
+		})
+		greet("world")
+		close(created)
+		<-finished
+		res <- greet("world")
+	}()
+	<-created
+	ctrl.Finish()
+	close(finished)
+
+	if r := <-res; r != "hello world" {
+		t.Fatalf("expect mock cancelled after finish, actual: %q", r)
+	}
+	ft.finish()
+	if len(ft.errors) != 0 {
+		t.Fatalf("expect no errors, actual: %v", ft.errors)
+	}
+}
@@ -0,0 +1,65 @@
+package mock_env
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/mock"
+)
+
+func lookupEnviron(key string) (string, bool) {
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, key+"=") {
+			return kv[len(key)+1:], true
+		}
+	}
+	return "", false
+}
+
+func TestSetenvParallel(t *testing.T) {
+	for _, region := range []string{"us", "eu", "ap"} {
+		region := region
+		t.Run(region, func(t *testing.T) {
+			t.Parallel()
+			mock.Setenv("XGO_TEST_REGION", region)
+
+			if v := os.Getenv("XGO_TEST_REGION"); v != region {
+				t.Fatalf("expect Getenv: %s, actual: %s", region, v)
+			}
+			if v, ok := os.LookupEnv("XGO_TEST_REGION"); !ok || v != region {
+				t.Fatalf("expect LookupEnv: %s, actual: %s %v", region, v, ok)
+			}
+			if v, ok := lookupEnviron("XGO_TEST_REGION"); !ok || v != region {
+				t.Fatalf("expect Environ: %s, actual: %s %v", region, v, ok)
+			}
+		})
+	}
+}
+
+func TestUnsetenv(t *testing.T) {
+	if _, ok := os.LookupEnv("PATH"); !ok {
+		t.Skip("PATH not set")
+	}
+	cancel := mock.Unsetenv("PATH")
+	if v, ok := os.LookupEnv("PATH"); ok {
+		t.Fatalf("expect PATH unset, actual: %s", v)
+	}
+	if _, ok := lookupEnviron("PATH"); ok {
+		t.Fatalf("expect PATH not in Environ")
+	}
+
+	// later override wins
+	cancelSet := mock.Setenv("PATH", "/xgo/bin")
+	if v := os.Getenv("PATH"); v != "/xgo/bin" {
+		t.Fatalf("expect PATH overridden, actual: %s", v)
+	}
+	if v, _ := lookupEnviron("PATH"); v != "/xgo/bin" {
+		t.Fatalf("expect PATH overridden in Environ, actual: %s", v)
+	}
+	cancelSet()
+	cancel()
+	if _, ok := os.LookupEnv("PATH"); !ok {
+		t.Fatalf("expect PATH restored")
+	}
+}
@@ -0,0 +1,91 @@
+package mock_execfake
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xhd2015/xgo/runtime/mock"
+	"github.com/xhd2015/xgo/runtime/mock/execfake"
+)
+
+func TestOutput(t *testing.T) {
+	fake := execfake.Install(t)
+	fake.Expect("git", "rev-parse", "HEAD").Stdout("abc\n")
+
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "abc\n" {
+		t.Fatalf("expect output abc, actual: %q", out)
+	}
+	calls := fake.Calls()
+	if len(calls) != 1 || strings.Join(calls[0], " ") != "git rev-parse HEAD" {
+		t.Fatalf("expect git rev-parse HEAD called, actual: %v", calls)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	fake := execfake.Install(t)
+	fake.Expect("kubectl-not-installed", "apply", mock.Any()).Stdout("applying\n").Stderr("denied\n").ExitCode(2)
+
+	out, err := exec.Command("kubectl-not-installed", "apply", "-f=deploy.yaml").CombinedOutput()
+	if string(out) != "applying\ndenied\n" {
+		t.Fatalf("expect combined output, actual: %q", out)
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 2 {
+		t.Fatalf("expect exit code 2, actual: %v", err)
+	}
+}
+
+func TestStartWait(t *testing.T) {
+	fake := execfake.Install(t)
+	fake.Expect("sleepy").Delay(50 * time.Millisecond)
+
+	cmd := exec.Command("sleepy")
+	err := cmd.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd.Process == nil || cmd.Process.Pid <= 0 {
+		t.Fatalf("expect process started")
+	}
+	if cmd.Path != "sleepy" {
+		t.Fatalf("expect path restored, actual: %s", cmd.Path)
+	}
+	err = cmd.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cmd.ProcessState.Success() {
+		t.Fatalf("expect success")
+	}
+}
+
+// fakeT records errors instead of failing the test
+type fakeT struct {
+	testing.TB
+	errors []string
+}
+
+func (c *fakeT) Errorf(format string, args ...interface{}) {
+	c.errors = append(c.errors, fmt.Sprintf(format, args...))
+}
+
+func TestUnexpected(t *testing.T) {
+	ft := &fakeT{TB: t}
+	execfake.Install(ft)
+
+	err := exec.Command("rm", "-rf", "/tmp/x").Run()
+	if err == nil {
+		t.Fatalf("expect unexpected command fail")
+	}
+	if len(ft.errors) != 1 || ft.errors[0] != `execfake: unexpected command: ["rm" "-rf" "/tmp/x"]` {
+		t.Fatalf("expect unexpected command reported, actual: %v", ft.errors)
+	}
+}
@@ -0,0 +1,159 @@
+package mock_fsfake
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/mock/fsfake"
+)
+
+func TestWriteDoesNotTouchDisk(t *testing.T) {
+	dir, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "readme.txt")
+
+	fs := fsfake.Install(t)
+
+	// read falls through to real disk
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello\n" {
+		t.Fatalf("expect read real file, actual: %q", data)
+	}
+
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("world\n"))
+	f.Close()
+
+	err = ioutil.WriteFile(filepath.Join(dir, "new.txt"), []byte("new"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ = ioutil.ReadFile(file)
+	if string(data) != "hello\nworld\n" {
+		t.Fatalf("expect read overlay file, actual: %q", data)
+	}
+	changes := fs.Changes()
+	if len(changes) != 2 {
+		t.Fatalf("expect 2 changes, actual: %d", len(changes))
+	}
+	if changes[0].Path != filepath.Join(dir, "new.txt") || changes[0].Kind != fsfake.ChangeKind_Added {
+		t.Fatalf("expect new.txt added, actual: %s %s", changes[0].Kind, changes[0].Path)
+	}
+	if changes[1].Path != file || changes[1].Kind != fsfake.ChangeKind_Modified {
+		t.Fatalf("expect readme.txt modified, actual: %s %s", changes[1].Kind, changes[1].Path)
+	}
+
+	fs.Cancel()
+	data, _ = ioutil.ReadFile(file)
+	if string(data) != "hello\n" {
+		t.Fatalf("expect real file not changed, actual: %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expect new.txt not created on disk, actual: %v", err)
+	}
+}
+
+func TestSeedAndRemove(t *testing.T) {
+	fs := fsfake.Install(t)
+	fs.Seed("testdata/config", "/etc/app")
+
+	data, err := ioutil.ReadFile("/etc/app/app.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "port: 8080\n" {
+		t.Fatalf("expect seeded file, actual: %q", data)
+	}
+	if changes := fs.Changes(); len(changes) != 0 {
+		t.Fatalf("expect no changes after seed, actual: %d", len(changes))
+	}
+
+	err = os.MkdirAll("/etc/app/conf.d", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile("/etc/app/conf.d/extra.yaml", []byte("debug: true\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Remove("/etc/app/app.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat("/etc/app/app.yaml"); !os.IsNotExist(err) {
+		t.Fatalf("expect app.yaml removed, actual: %v", err)
+	}
+
+	entries, err := ioutil.ReadDir("/etc/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "conf.d" || !entries[0].IsDir() {
+		t.Fatalf("expect only conf.d in /etc/app, actual: %d entries", len(entries))
+	}
+
+	changes := fs.Changes()
+	if len(changes) != 2 {
+		t.Fatalf("expect 2 changes, actual: %d", len(changes))
+	}
+	if changes[0].Path != "/etc/app/app.yaml" || changes[0].Kind != fsfake.ChangeKind_Removed {
+		t.Fatalf("expect app.yaml removed, actual: %s %s", changes[0].Kind, changes[0].Path)
+	}
+	if changes[1].Path != "/etc/app/conf.d/extra.yaml" || changes[1].Kind != fsfake.ChangeKind_Added {
+		t.Fatalf("expect extra.yaml added, actual: %s %s", changes[1].Kind, changes[1].Path)
+	}
+}
+
+func TestMkdirAndRemoveAll(t *testing.T) {
+	dir, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := fsfake.Install(t)
+
+	err = os.Mkdir(filepath.Join(dir, "sub"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); !os.IsExist(err) {
+		t.Fatalf("expect sub exists, actual: %v", err)
+	}
+	err = os.RemoveAll(filepath.Join(dir, "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "config", "app.yaml")); !os.IsNotExist(err) {
+		t.Fatalf("expect config/app.yaml removed, actual: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if len(names) != 2 || names[0] != "readme.txt" || names[1] != "sub" || !entries[1].IsDir() {
+		t.Fatalf("expect readme.txt and sub, actual: %v", names)
+	}
+
+	fs.Cancel()
+	if _, err := os.Stat(filepath.Join(dir, "config", "app.yaml")); err != nil {
+		t.Fatalf("expect real config/app.yaml kept, actual: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "sub")); !os.IsNotExist(err) {
+		t.Fatalf("expect sub not created on disk, actual: %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+//go:build go1.18
+// +build go1.18
+
+package mock_generic
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/core"
+	"github.com/xhd2015/xgo/runtime/mock"
+)
+
+func Describe[T any](v T) string {
+	return fmt.Sprintf("%T", v)
+}
+
+func describeInt(v int) string {
+	return fmt.Sprintf("%T", v)
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestMockGenericAllInstances -v ./test/mock_generic
+func TestMockGenericAllInstances(t *testing.T) {
+	mock.MockGeneric(Describe[int], func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+		if mock.IsInstance(Describe[bool]) {
+			return mock.ErrCallOld
+		}
+		results.GetFieldIndex(0).Set(fmt.Sprintf("mock %v", args.GetFieldIndex(0).Value()))
+		return nil
+	})
+
+	if s := Describe(1); s != "mock 1" {
+		t.Fatalf("expect Describe(1) to be %q, actual: %q", "mock 1", s)
+	}
+	if s := Describe("a"); s != "mock a" {
+		t.Fatalf("expect Describe(\"a\") to be %q, actual: %q", "mock a", s)
+	}
+	if s := Describe(true); s != "bool" {
+		t.Fatalf("expect Describe(true) not affected, actual: %q", s)
+	}
+}
+
+type Pair[K comparable, V any] struct {
+	Key K
+	Val V
+}
+
+func (c *Pair[K, V]) Describe() string {
+	return fmt.Sprintf("%v=%v", c.Key, c.Val)
+}
+
+func Convert[From any, To any](v From) (res To) {
+	return res
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestMockGenericTypeArgs -v ./test/mock_generic
+func TestMockGenericTypeArgs(t *testing.T) {
+	var typeArgs [][]reflect.Type
+	interceptor := func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+		typeArgs = append(typeArgs, mock.TypeArgs())
+		return mock.ErrCallOld
+	}
+	mock.MockGeneric(Describe[int], interceptor)
+	mock.MockGeneric(Convert[int, string], interceptor)
+	mock.MockGeneric((*Pair[string, int]).Describe, interceptor)
+
+	Describe(1)
+	Describe("a")
+	Convert[int, bool](1)
+	(&Pair[string, error]{Key: "k"}).Describe()
+
+	expect := [][]reflect.Type{
+		{reflect.TypeOf(0)},
+		{reflect.TypeOf("")},
+		{reflect.TypeOf(0), reflect.TypeOf(false)},
+		{reflect.TypeOf(""), reflect.TypeOf((*error)(nil)).Elem()},
+	}
+	if !reflect.DeepEqual(typeArgs, expect) {
+		t.Fatalf("expect type args %v, actual: %v", expect, typeArgs)
+	}
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestMockByNameGenericAllInstances -v ./test/mock_generic
+func TestMockByNameGenericAllInstances(t *testing.T) {
+	mock.MockByName("github.com/xhd2015/xgo/runtime/test/mock_generic", "Describe", func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+		results.GetFieldIndex(0).Set("mock")
+		return nil
+	})
+
+	if s := Describe(1); s != "mock" {
+		t.Fatalf("expect Describe(1) to be %q, actual: %q", "mock", s)
+	}
+	if s := Describe(1.5); s != "mock" {
+		t.Fatalf("expect Describe(1.5) to be %q, actual: %q", "mock", s)
+	}
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestMockGenericRejectNonGeneric -v ./test/mock_generic
+func TestMockGenericRejectNonGeneric(t *testing.T) {
+	var pe interface{}
+	func() {
+		defer func() {
+			pe = recover()
+		}()
+		mock.MockGeneric(describeInt, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+			return nil
+		})
+	}()
+	if pe == nil {
+		t.Fatalf("expect MockGeneric on non-generic function to panic")
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !go1.18
+// +build !go1.18
+
+package mock_goroutine
+
+import (
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/mock"
+	"github.com/xhd2015/xgo/runtime/trap"
+)
+
+// go1.17: go run ./cmd/xgo test --project-dir runtime -run TestGoroutinesUnsupported -v ./test/mock_goroutine
+func TestGoroutinesUnsupported(t *testing.T) {
+	g, err := mock.Goroutines(mock.Capture)
+	if err != trap.ErrGoHandlerUnsupported {
+		t.Fatalf("expect err %v, actual: %v", trap.ErrGoHandlerUnsupported, err)
+	}
+	if g != nil {
+		t.Fatalf("expect no control returned")
+	}
+}
@@ -0,0 +1,254 @@
+//go:build go1.18
+// +build go1.18
+
+package mock_goroutine
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/mock"
+)
+
+type notifier struct {
+	mutex sync.Mutex
+	sent  []string
+}
+
+func (c *notifier) send(msg string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.sent = append(c.sent, msg)
+}
+
+func (c *notifier) getSent() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]string(nil), c.sent...)
+}
+
+func notify(n *notifier, users ...string) {
+	for _, user := range users {
+		go n.send("hello " + user)
+	}
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestGoroutinesRunInline -v ./test/mock_goroutine
+func TestGoroutinesRunInline(t *testing.T) {
+	g, err := mock.Goroutines(mock.RunInline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := &notifier{}
+	notify(n, "A", "B")
+
+	sent := fmt.Sprint(n.getSent())
+	expectSent := "[hello A hello B]"
+	if sent != expectSent {
+		t.Fatalf("expect sent %s, actual: %s", expectSent, sent)
+	}
+	if g.Count() != 2 {
+		t.Fatalf("expect count 2, actual: %d", g.Count())
+	}
+	if g.Pending() != 0 {
+		t.Fatalf("expect pending 0, actual: %d", g.Pending())
+	}
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestGoroutinesCapture -v ./test/mock_goroutine
+func TestGoroutinesCapture(t *testing.T) {
+	g, err := mock.Goroutines(mock.Capture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := &notifier{}
+	notify(n, "A", "B")
+
+	if len(n.getSent()) != 0 {
+		t.Fatalf("expect nothing sent before drain, actual: %v", n.getSent())
+	}
+	if g.Count() != 2 {
+		t.Fatalf("expect count 2, actual: %d", g.Count())
+	}
+	if g.Pending() != 2 {
+		t.Fatalf("expect pending 2, actual: %d", g.Pending())
+	}
+	ran := g.Drain()
+	if ran != 2 {
+		t.Fatalf("expect drain 2, actual: %d", ran)
+	}
+	sent := fmt.Sprint(n.getSent())
+	expectSent := "[hello A hello B]"
+	if sent != expectSent {
+		t.Fatalf("expect sent %s, actual: %s", expectSent, sent)
+	}
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestGoroutinesCaptureArgsEvaluated -v ./test/mock_goroutine
+func TestGoroutinesCaptureArgsEvaluated(t *testing.T) {
+	g, err := mock.Goroutines(mock.Capture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := &notifier{}
+	msg := "first"
+	go n.send(msg)
+	msg = "second"
+	g.Drain()
+
+	sent := fmt.Sprint(n.getSent())
+	expectSent := "[first]"
+	if sent != expectSent {
+		t.Fatalf("expect sent %s, actual: %s", expectSent, sent)
+	}
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestGoroutinesDrainNested -v ./test/mock_goroutine
+func TestGoroutinesDrainNested(t *testing.T) {
+	g, err := mock.Goroutines(mock.Capture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := &notifier{}
+	go func() {
+		n.send("outer")
+		go n.send("inner")
+	}()
+	ran := g.Drain()
+	if ran != 2 {
+		t.Fatalf("expect drain 2, actual: %d", ran)
+	}
+	sent := fmt.Sprint(n.getSent())
+	expectSent := "[outer inner]"
+	if sent != expectSent {
+		t.Fatalf("expect sent %s, actual: %s", expectSent, sent)
+	}
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestGoroutinesCancel -v ./test/mock_goroutine
+func TestGoroutinesCancel(t *testing.T) {
+	g, err := mock.Goroutines(mock.Capture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := &notifier{}
+	go n.send("captured")
+	g.Cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		n.send("started")
+	}()
+	wg.Wait()
+
+	sent := fmt.Sprint(n.getSent())
+	expectSent := "[started]"
+	if sent != expectSent {
+		t.Fatalf("expect sent %s, actual: %s", expectSent, sent)
+	}
+	if g.Count() != 1 {
+		t.Fatalf("expect count 1, actual: %d", g.Count())
+	}
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestGoroutinesOtherGoroutineNotAffected -v ./test/mock_goroutine
+func TestGoroutinesOtherGoroutineNotAffected(t *testing.T) {
+	n := &notifier{}
+	start := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		<-start
+		go func() {
+			n.send("real")
+			close(done)
+		}()
+	}()
+
+	g, err := mock.Goroutines(mock.Capture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(start)
+	<-done
+	if g.Count() != 0 {
+		t.Fatalf("expect count 0, actual: %d", g.Count())
+	}
+}
+
+func httpGet(url string) (string, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func newHelloServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+}
+
+// goroutines started by net/http, i.e. connection
+// read and write loops, must not be taken over
+//
+// go run ./cmd/xgo test --project-dir runtime -run TestGoroutinesRunInlineHTTP -v ./test/mock_goroutine
+func TestGoroutinesRunInlineHTTP(t *testing.T) {
+	server := newHelloServer()
+	defer server.Close()
+
+	g, err := mock.Goroutines(mock.RunInline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Cancel()
+
+	body, err := httpGet(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "hello" {
+		t.Fatalf("expect body %q, actual: %q", "hello", body)
+	}
+	if g.Count() != 0 {
+		t.Fatalf("expect go statements of net/http not taken over, actual count: %d", g.Count())
+	}
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestGoroutinesCaptureHTTP -v ./test/mock_goroutine
+func TestGoroutinesCaptureHTTP(t *testing.T) {
+	server := newHelloServer()
+	defer server.Close()
+
+	g, err := mock.Goroutines(mock.Capture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Cancel()
+
+	var body string
+	go func() {
+		body, err = httpGet(server.URL)
+	}()
+	if g.Pending() != 1 {
+		t.Fatalf("expect 1 pending, actual: %d", g.Pending())
+	}
+	g.Drain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "hello" {
+		t.Fatalf("expect body %q, actual: %q", "hello", body)
+	}
+	if g.Count() != 1 {
+		t.Fatalf("expect only the go statement of the test taken over, actual count: %d", g.Count())
+	}
+}
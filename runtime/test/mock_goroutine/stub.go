@@ -0,0 +1 @@
+package mock_goroutine
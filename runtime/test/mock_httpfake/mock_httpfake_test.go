@@ -0,0 +1,109 @@
+package mock_httpfake
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/mock/httpfake"
+)
+
+func getBody(t *testing.T, resp *http.Response) string {
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestReply(t *testing.T) {
+	fake := httpfake.Install(t)
+	fake.On("GET", "https://api.example.com/users/*").
+		Header("Content-Type", "application/json").
+		Reply(200, `{"name":"test"}`)
+	fake.On("POST", "api.example.com/users").Reply(201, "created")
+
+	resp, err := http.Get("https://api.example.com/users/1?verbose=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("expect 200 json, actual: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if body := getBody(t, resp); body != `{"name":"test"}` {
+		t.Fatalf("expect body, actual: %q", body)
+	}
+
+	resp, err = http.Post("http://api.example.com/users", "text/plain", strings.NewReader("alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 201 {
+		t.Fatalf("expect 201, actual: %d", resp.StatusCode)
+	}
+
+	requests := fake.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("expect 2 requests, actual: %d", len(requests))
+	}
+	if requests[1].Method != "POST" || string(requests[1].Body) != "alice" {
+		t.Fatalf("expect POST alice logged, actual: %s %q", requests[1].Method, requests[1].Body)
+	}
+}
+
+func TestHandleAndFail(t *testing.T) {
+	fake := httpfake.Install(t)
+	fake.On("*", "api.example.com/echo").Handle(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(r.URL.Query().Get("msg")))
+	})
+	errDown := errors.New("connection refused")
+	fake.On("GET", "api.example.com/down").Fail(errDown)
+
+	resp, err := http.Get("http://api.example.com/echo?msg=hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expect 202, actual: %d", resp.StatusCode)
+	}
+	if body := getBody(t, resp); body != "hello" {
+		t.Fatalf("expect hello, actual: %q", body)
+	}
+
+	_, err = http.Get("http://api.example.com/down")
+	if !errors.Is(err, errDown) {
+		t.Fatalf("expect errDown, actual: %v", err)
+	}
+}
+
+// fakeT records errors instead of failing the test
+type fakeT struct {
+	testing.TB
+	errors []string
+}
+
+func (c *fakeT) Errorf(format string, args ...interface{}) {
+	c.errors = append(c.errors, fmt.Sprintf(format, args...))
+}
+
+func TestUnmatched(t *testing.T) {
+	ft := &fakeT{TB: t}
+	fake := httpfake.Install(ft)
+	fake.On("GET", "api.example.com/users").Reply(200, "")
+
+	_, err := http.Get("http://api.example.com/unknown")
+	if err == nil {
+		t.Fatalf("expect unmatched request fail")
+	}
+	if len(ft.errors) != 1 || ft.errors[0] != "httpfake: unmatched request GET http://api.example.com/unknown" {
+		t.Fatalf("expect unmatched request reported, actual: %v", ft.errors)
+	}
+	if len(fake.Unmatched()) != 1 {
+		t.Fatalf("expect 1 unmatched request, actual: %d", len(fake.Unmatched()))
+	}
+}
@@ -0,0 +1,90 @@
+//go:build go1.18
+// +build go1.18
+
+package mock_interface
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/core"
+	"github.com/xhd2015/xgo/runtime/mock"
+	"github.com/xhd2015/xgo/runtime/mock/typed"
+)
+
+type Store interface {
+	Save(key string) string
+}
+
+type fileStore struct {
+	dir string
+}
+
+func (c *fileStore) Save(key string) string {
+	return "file:" + c.dir + "/" + key
+}
+
+type memStore struct{}
+
+func (c memStore) Save(key string) string {
+	return "mem:" + key
+}
+
+// not a Store
+type logger struct{}
+
+func (c *logger) Save(key string) int {
+	return len(key)
+}
+
+func newStore(kind string) Store {
+	if kind == "file" {
+		return &fileStore{dir: "/tmp"}
+	}
+	return memStore{}
+}
+
+func TestMockInterface(t *testing.T) {
+	mock.MockInterface((*Store)(nil), "Save", func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+		results.GetFieldIndex(0).Set("mock:" + args.GetField("key").Value().(string))
+		return nil
+	})
+
+	for _, kind := range []string{"file", "mem"} {
+		res := newStore(kind).Save("a")
+		if res != "mock:a" {
+			t.Fatalf("expect %s store mocked, actual: %q", kind, res)
+		}
+	}
+	l := &logger{}
+	if n := l.Save("abc"); n != 3 {
+		t.Fatalf("expect logger not affected, actual: %d", n)
+	}
+}
+
+func TestMockInterfaceByName(t *testing.T) {
+	cancel := mock.MockInterfaceByName("github.com/xhd2015/xgo/runtime/test/mock_interface", "Store", "Save", func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+		results.GetFieldIndex(0).Set("mock")
+		return nil
+	})
+	res := newStore("file").Save("a")
+	if res != "mock" {
+		t.Fatalf("expect file store mocked, actual: %q", res)
+	}
+	cancel()
+	res = newStore("file").Save("a")
+	if res != "file:/tmp/a" {
+		t.Fatalf("expect mock cancelled, actual: %q", res)
+	}
+}
+
+func TestMockInterfaceTyped(t *testing.T) {
+	typed.MockInterface[Store]("Save", func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+		results.GetFieldIndex(0).Set("mock")
+		return nil
+	})
+	res := newStore("mem").Save("a")
+	if res != "mock" {
+		t.Fatalf("expect mem store mocked, actual: %q", res)
+	}
+}
@@ -0,0 +1,107 @@
+package mock_isolate
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/mock"
+)
+
+const pkgPath = "github.com/xhd2015/xgo/runtime/test/mock_isolate"
+
+type config struct {
+	Name    string
+	retries int
+	tags    []string
+}
+
+var counter int
+var cfg = &config{Name: "default", retries: 3, tags: []string{"a"}}
+var registry = map[string]*config{}
+var names = []string{"x", "y"}
+var handler interface{} = &config{Name: "handler"}
+
+type pool struct {
+	mu    sync.Mutex
+	conns []string
+}
+
+var db = &pool{conns: []string{"c1"}}
+var dbs = map[string]*pool{"main": db}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestIsolateVarsRestore -v ./test/mock_isolate
+func TestIsolateVarsRestore(t *testing.T) {
+	origCfg := cfg
+	origRegistry := registry
+	t.Run("mutate", func(t *testing.T) {
+		mock.IsolateVars(t, pkgPath)
+		counter = 10
+		cfg.Name = "changed"
+		cfg.retries = 0
+		cfg.tags[0] = "b"
+		registry["new"] = &config{}
+		names[0] = "z"
+		names = append(names, "w")
+		handler.(*config).Name = "changed"
+	})
+	if counter != 0 {
+		t.Fatalf("expect counter restored to 0, actual: %d", counter)
+	}
+	if cfg != origCfg {
+		t.Fatalf("expect cfg pointer kept")
+	}
+	if cfg.Name != "default" || cfg.retries != 3 || cfg.tags[0] != "a" {
+		t.Fatalf("expect cfg restored, actual: %+v", *cfg)
+	}
+	if len(registry) != 0 {
+		t.Fatalf("expect registry restored to empty, actual: %v", registry)
+	}
+	registry["check"] = nil
+	if len(origRegistry) != 1 {
+		t.Fatalf("expect registry map kept")
+	}
+	delete(registry, "check")
+	if len(names) != 2 || names[0] != "x" {
+		t.Fatalf("expect names restored, actual: %v", names)
+	}
+	if handler.(*config).Name != "handler" {
+		t.Fatalf("expect handler restored, actual: %s", handler.(*config).Name)
+	}
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestIsolateVarsPattern -v ./test/mock_isolate
+func TestIsolateVarsPattern(t *testing.T) {
+	t.Run("mutate", func(t *testing.T) {
+		mock.IsolateVars(t, "github.com/xhd2015/xgo/runtime/test/other/...")
+		counter = 20
+	})
+	defer func() {
+		counter = 0
+	}()
+	if counter != 20 {
+		t.Fatalf("expect counter not restored, actual: %d", counter)
+	}
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestIsolateVarsKeepSyncByReference -v ./test/mock_isolate
+func TestIsolateVarsKeepSyncByReference(t *testing.T) {
+	t.Run("mutate", func(t *testing.T) {
+		mock.IsolateVars(t, pkgPath)
+		db.mu.Lock()
+		db.conns = append(db.conns, "c2")
+		db.mu.Unlock()
+		dbs["other"] = &pool{}
+	})
+	defer func() {
+		db.conns = db.conns[:1]
+	}()
+	if len(db.conns) != 2 {
+		t.Fatalf("expect db kept by reference, actual conns: %v", db.conns)
+	}
+	if dbs["main"] != db {
+		t.Fatalf("expect dbs to hold db")
+	}
+	if len(dbs) != 1 {
+		t.Fatalf("expect dbs restored, actual: %v", dbs)
+	}
+}
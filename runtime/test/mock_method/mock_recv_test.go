@@ -0,0 +1,73 @@
+package mock_method
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/core"
+	"github.com/xhd2015/xgo/runtime/mock"
+)
+
+type batch struct {
+	name  string
+	items []string
+}
+
+func (c batch) Size() int {
+	return len(c.items)
+}
+
+func (c *batch) Name() string {
+	return c.name
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestMockNonComparableRecv -v ./test/mock_method
+func TestMockNonComparableRecv(t *testing.T) {
+	b1 := batch{name: "b1", items: []string{"a"}}
+	b2 := batch{name: "b2", items: []string{"a", "b"}}
+	mock.Mock(b1.Size, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+		results.GetFieldIndex(0).Set(100)
+		return nil
+	})
+
+	if n := b1.Size(); n != 100 {
+		t.Fatalf("expect b1.Size() to be 100, actual: %d", n)
+	}
+	if n := b2.Size(); n != 2 {
+		t.Fatalf("expect b2.Size() not affected, actual: %d", n)
+	}
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestMockPtrRecvIdentity -v ./test/mock_method
+func TestMockPtrRecvIdentity(t *testing.T) {
+	b1 := &batch{name: "b"}
+	b2 := &batch{name: "b"}
+	mock.Mock(b1.Name, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+		results.GetFieldIndex(0).Set("mock")
+		return nil
+	})
+
+	if s := b1.Name(); s != "mock" {
+		t.Fatalf("expect b1.Name() to be %q, actual: %q", "mock", s)
+	}
+	if s := b2.Name(); s != "b" {
+		t.Fatalf("expect equal-valued b2.Name() not affected, actual: %q", s)
+	}
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestMockMatchRecv -v ./test/mock_method
+func TestMockMatchRecv(t *testing.T) {
+	mock.MockMethodByName(&batch{}, "Name", func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+		results.GetFieldIndex(0).Set("mock")
+		return nil
+	}, mock.MatchRecv(func(recv interface{}) bool {
+		return recv.(*batch).name == "b1"
+	}))
+
+	if s := (&batch{name: "b1"}).Name(); s != "mock" {
+		t.Fatalf("expect b1.Name() to be %q, actual: %q", "mock", s)
+	}
+	if s := (&batch{name: "b2"}).Name(); s != "b2" {
+		t.Fatalf("expect b2.Name() not affected, actual: %q", s)
+	}
+}
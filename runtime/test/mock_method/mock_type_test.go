@@ -0,0 +1,82 @@
+//go:build go1.18
+// +build go1.18
+
+package mock_method
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/core"
+	"github.com/xhd2015/xgo/runtime/mock"
+	"github.com/xhd2015/xgo/runtime/mock/typed"
+)
+
+type client struct {
+	addr string
+}
+
+func (c *client) Do(req string) string {
+	return c.addr + ":" + req
+}
+
+func (c client) Addr() string {
+	return c.addr
+}
+
+func newClient(addr string) *client {
+	return &client{addr: addr}
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestMockMethodExpr -v ./test/mock_method
+func TestMockMethodExpr(t *testing.T) {
+	mock.MockMethod((*client).Do, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+		c := args.GetFieldIndex(0).Value().(*client)
+		results.GetFieldIndex(0).Set("mock " + c.addr)
+		return nil
+	})
+
+	for _, addr := range []string{"a", "b"} {
+		expect := "mock " + addr
+		if s := newClient(addr).Do("ping"); s != expect {
+			t.Fatalf("expect Do() to be %q, actual: %q", expect, s)
+		}
+	}
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestMockMethodRejectBound -v ./test/mock_method
+func TestMockMethodRejectBound(t *testing.T) {
+	var pe interface{}
+	func() {
+		defer func() {
+			pe = recover()
+		}()
+		mock.MockMethod(newClient("a").Do, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+			return nil
+		})
+	}()
+	if pe == nil {
+		t.Fatalf("expect MockMethod on bound method to panic")
+	}
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestMockType -v ./test/mock_method
+func TestMockType(t *testing.T) {
+	mock.MockType((*client)(nil), "Do", func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+		results.GetFieldIndex(0).Set("mock")
+		return nil
+	})
+	// value receiver method looked up via *client
+	typed.MockType[*client]("Addr", func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+		results.GetFieldIndex(0).Set("mock addr")
+		return nil
+	})
+
+	c := newClient("a")
+	if s := c.Do("ping"); s != "mock" {
+		t.Fatalf("expect Do() to be %q, actual: %q", "mock", s)
+	}
+	if s := c.Addr(); s != "mock addr" {
+		t.Fatalf("expect Addr() to be %q, actual: %q", "mock addr", s)
+	}
+}
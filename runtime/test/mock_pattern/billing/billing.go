@@ -0,0 +1,5 @@
+package billing
+
+func Pay(amount int) string {
+	return "pay"
+}
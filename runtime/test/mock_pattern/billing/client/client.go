@@ -0,0 +1,21 @@
+package client
+
+type Client struct {
+	name string
+}
+
+func New(name string) *Client {
+	return &Client{name: name}
+}
+
+func (c *Client) Charge(amount int) string {
+	return "charge " + c.name
+}
+
+func (c *Client) Refund(amount int) string {
+	return "refund " + c.name
+}
+
+func (c Client) Name() string {
+	return c.name
+}
@@ -0,0 +1,85 @@
+package mock_pattern
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/core"
+	"github.com/xhd2015/xgo/runtime/mock"
+	"github.com/xhd2015/xgo/runtime/test/mock_pattern/billing"
+	"github.com/xhd2015/xgo/runtime/test/mock_pattern/billing/client"
+)
+
+const billingPkg = "github.com/xhd2015/xgo/runtime/test/mock_pattern/billing"
+
+func mockResult(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+	results.GetFieldIndex(0).Set("mock " + fn.IdentityName)
+	return nil
+}
+
+func TestMockPatternPtrMethods(t *testing.T) {
+	cancel := mock.MockPattern(billingPkg+"/client", "(*Client).*", mockResult)
+
+	c := client.New("a")
+	if res := c.Charge(1); res != "mock (*Client).Charge" {
+		t.Fatalf("expect Charge mocked, actual: %q", res)
+	}
+	if res := c.Refund(1); res != "mock (*Client).Refund" {
+		t.Fatalf("expect Refund mocked, actual: %q", res)
+	}
+	// value receiver method is not matched
+	if res := c.Name(); res != "a" {
+		t.Fatalf("expect Name not mocked, actual: %q", res)
+	}
+	// other package is not matched
+	if res := billing.Pay(1); res != "pay" {
+		t.Fatalf("expect Pay not mocked, actual: %q", res)
+	}
+
+	cancel()
+	if res := c.Charge(1); res != "charge a" {
+		t.Fatalf("expect mock cancelled, actual: %q", res)
+	}
+}
+
+func TestMockPatternSubPackages(t *testing.T) {
+	mock.MockPattern(billingPkg+"/...", "*", func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+		if fn.Name == "New" {
+			return mock.ErrCallOld
+		}
+		return mockResult(ctx, fn, args, results)
+	})
+
+	if res := billing.Pay(1); res != "mock Pay" {
+		t.Fatalf("expect Pay mocked, actual: %q", res)
+	}
+	c := client.New("a")
+	if res := c.Name(); res != "mock Client.Name" {
+		t.Fatalf("expect Name mocked, actual: %q", res)
+	}
+}
+
+func TestMockPatternGlob(t *testing.T) {
+	mock.MockPattern(billingPkg+"/client", "(*Client).?efund", mockResult)
+
+	c := client.New("a")
+	if res := c.Charge(1); res != "charge a" {
+		t.Fatalf("expect Charge not mocked, actual: %q", res)
+	}
+	if res := c.Refund(1); res != "mock (*Client).Refund" {
+		t.Fatalf("expect Refund mocked, actual: %q", res)
+	}
+}
+
+func TestMockPatternNoMatch(t *testing.T) {
+	var pe interface{}
+	func() {
+		defer func() {
+			pe = recover()
+		}()
+		mock.MockPattern(billingPkg, "NotExist*", mockResult)
+	}()
+	if pe == nil {
+		t.Fatalf("expect panic when no function matched")
+	}
+}
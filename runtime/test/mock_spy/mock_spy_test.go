@@ -0,0 +1,86 @@
+package mock_spy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/mock"
+)
+
+var errNegative = errors.New("negative")
+
+func sqrt(n int) (int, error) {
+	if n < 0 {
+		return 0, errNegative
+	}
+	r := 0
+	for (r+1)*(r+1) <= n {
+		r++
+	}
+	return r, nil
+}
+
+func mustPositive(n int) int {
+	if n <= 0 {
+		panic("not positive")
+	}
+	return n
+}
+
+func TestSpyRecordsCalls(t *testing.T) {
+	spy := mock.Spy(sqrt)
+
+	r, err := sqrt(16)
+	if err != nil || r != 4 {
+		t.Fatalf("expect spy not altering behavior, actual: %d %v", r, err)
+	}
+	_, err = sqrt(-1)
+	if err != errNegative {
+		t.Fatalf("expect err %v, actual: %v", errNegative, err)
+	}
+
+	calls := spy.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("expect 2 calls, actual: %d", len(calls))
+	}
+	if calls[0].Args[0] != 16 || calls[0].Results[0] != 4 || calls[0].Err != nil {
+		t.Fatalf("unexpected first call: %+v", calls[0])
+	}
+	last := spy.LastCall()
+	if last.Err != errNegative {
+		t.Fatalf("expect last call err %v, actual: %v", errNegative, last.Err)
+	}
+	if !spy.CalledWith(16) {
+		t.Fatalf("expect CalledWith(16)")
+	}
+	if spy.CalledWith(mock.MatchFunc(func(v interface{}) bool { return v.(int) > 100 })) {
+		t.Fatalf("expect not CalledWith(>100)")
+	}
+}
+
+func TestSpyRecordsPanic(t *testing.T) {
+	spy := mock.Spy(mustPositive)
+	func() {
+		defer func() {
+			recover()
+		}()
+		mustPositive(-1)
+	}()
+	last := spy.LastCall()
+	if last == nil {
+		t.Fatalf("expect call recorded")
+	}
+	if last.Panic != "not positive" {
+		t.Fatalf("expect panic recorded, actual: %v", last.Panic)
+	}
+}
+
+func TestSpyCancel(t *testing.T) {
+	spy := mock.Spy(sqrt)
+	sqrt(1)
+	spy.Cancel()
+	sqrt(4)
+	if spy.Count() != 1 {
+		t.Fatalf("expect 1 call after cancel, actual: %d", spy.Count())
+	}
+}
@@ -0,0 +1,117 @@
+package mock_stub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/mock"
+)
+
+type User struct {
+	ID   string
+	Name string
+}
+
+func Fetch(ctx context.Context, id string, limit int) (*User, error) {
+	return &User{ID: id, Name: "real"}, nil
+}
+
+func TestStubWhenReturn(t *testing.T) {
+	mockUser := &User{ID: "id1", Name: "mock"}
+	mock.On(Fetch).When(mock.Any(), mock.Eq("id1"), mock.Any()).Return(mockUser, nil)
+
+	user, err := Fetch(context.Background(), "id1", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user != mockUser {
+		t.Fatalf("expect user to be mocked, actual: %+v", user)
+	}
+
+	// fall through
+	user, err = Fetch(context.Background(), "id2", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Name != "real" {
+		t.Fatalf("expect unmatched call to use original function, actual: %+v", user)
+	}
+}
+
+func TestStubSequencedReturn(t *testing.T) {
+	mockUser := &User{ID: "id1", Name: "mock"}
+	mockErr := errors.New("not found")
+	mock.On(Fetch).When(mock.Any(), mock.Regex("^id"), 10).Return(mockUser, nil).Times(2).Then().Return(nil, mockErr)
+
+	for i := 0; i < 2; i++ {
+		user, err := Fetch(context.Background(), "id1", 10)
+		if err != nil || user != mockUser {
+			t.Fatalf("call %d: expect mock user, actual: %+v %v", i, user, err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		user, err := Fetch(context.Background(), "id1", 10)
+		if err != mockErr || user != nil {
+			t.Fatalf("expect mock err, actual: %+v %v", user, err)
+		}
+	}
+}
+
+func TestStubMatchFunc(t *testing.T) {
+	mock.On(Fetch).When(mock.Any(), mock.Any(), mock.MatchFunc(func(v interface{}) bool {
+		return v.(int) > 100
+	})).Return(nil, errors.New("limit exceeded"))
+
+	_, err := Fetch(context.Background(), "id1", 1000)
+	if err == nil || err.Error() != "limit exceeded" {
+		t.Fatalf("expect limit exceeded, actual: %v", err)
+	}
+	_, err = Fetch(context.Background(), "id1", 10)
+	if err != nil {
+		t.Fatalf("expect no error, actual: %v", err)
+	}
+}
+
+func TestStubReturnTypeMismatch(t *testing.T) {
+	var panicErr interface{}
+	func() {
+		defer func() {
+			panicErr = recover()
+		}()
+		mock.On(Fetch).Return("user", nil)
+	}()
+	if panicErr == nil {
+		t.Fatalf("expect mismatched return type to panic")
+	}
+	msg := fmt.Sprint(panicErr)
+	expectMsg := "results should have type: (*mock_stub.User,error), actual: (string,nil)"
+	if !strings.Contains(msg, expectMsg) {
+		t.Fatalf("expect panic %q, actual: %s", expectMsg, msg)
+	}
+}
+
+func TestEqNumberConversion(t *testing.T) {
+	testCases := []struct {
+		expect interface{}
+		v      interface{}
+		match  bool
+	}{
+		{1, int64(1), true},
+		{1, uint8(1), true},
+		{1, 1.0, true},
+		{1.5, 1, false},
+		{-1, uint64(math.MaxUint64), false},
+		{-1, uint8(255), false},
+		{256, uint8(0), false},
+		{0.1, float32(0.1), false},
+	}
+	for _, tc := range testCases {
+		if match := mock.Eq(tc.expect).Match(tc.v); match != tc.match {
+			t.Fatalf("expect Eq(%#v).Match(%T(%v)) to be %v, actual: %v", tc.expect, tc.v, tc.v, tc.match, match)
+		}
+	}
+}
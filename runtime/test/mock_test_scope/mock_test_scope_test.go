@@ -0,0 +1,69 @@
+package mock_test_scope
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/core"
+	"github.com/xhd2015/xgo/runtime/mock"
+)
+
+func greet() string {
+	return "real"
+}
+
+func stub(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+	results.GetFieldIndex(0).Set("mock")
+	return nil
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestForTestStartedGoroutine -v ./test/mock_test_scope
+func TestForTestStartedGoroutine(t *testing.T) {
+	start := make(chan struct{})
+	res := make(chan string)
+	// started before the mock is set up
+	go func() {
+		<-start
+		res <- greet()
+	}()
+
+	mock.Mock(greet, stub, mock.ForTest(t))
+	close(start)
+
+	if s := <-res; s != "mock" {
+		t.Fatalf("expect greet() in started goroutine to be %q, actual: %q", "mock", s)
+	}
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestForTestSubtests -v ./test/mock_test_scope
+func TestForTestSubtests(t *testing.T) {
+	t.Run("parallel", func(t *testing.T) {
+		// resumed after the parent returns
+		t.Parallel()
+		if s := greet(); s != "mock" {
+			t.Errorf("expect greet() in parallel subtest to be %q, actual: %q", "mock", s)
+		}
+	})
+	t.Run("sub", func(t *testing.T) {
+		mock.Mock(greet, func(ctx context.Context, fn *core.FuncInfo, args, results core.Object) error {
+			results.GetFieldIndex(0).Set("sub")
+			return nil
+		}, mock.ForTest(t))
+		if s := greet(); s != "sub" {
+			t.Fatalf("expect subtest mock to override, actual: %q", s)
+		}
+	})
+
+	// the parallel subtest is already started
+	mock.Mock(greet, stub, mock.ForTest(t))
+}
+
+// go run ./cmd/xgo test --project-dir runtime -run TestForTestCleared -v ./test/mock_test_scope
+func TestForTestCleared(t *testing.T) {
+	t.Run("sub", func(t *testing.T) {
+		mock.Mock(greet, stub, mock.ForTest(t))
+	})
+	if s := greet(); s != "real" {
+		t.Fatalf("expect mock cleared after subtest finished, actual: %q", s)
+	}
+}
@@ -0,0 +1,99 @@
+package mock_wrap
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/mock"
+)
+
+var fetchCount int
+
+func fetch(ctx context.Context, id string) (string, error) {
+	fetchCount++
+	if fetchCount < 3 {
+		return "", errors.New("temporary")
+	}
+	return "user:" + id, nil
+}
+
+func greet(name string) string {
+	return "hello " + name + "!" + helper()
+}
+
+func helper() string {
+	return ""
+}
+
+type Client struct {
+	prefix string
+}
+
+func (c *Client) Join(sep string, parts ...string) string {
+	return c.prefix + strings.Join(parts, sep)
+}
+
+func TestWrapRetry(t *testing.T) {
+	fetchCount = 0
+	mock.Wrap(fetch, func(inv *mock.Invocation) {
+		for i := 0; i < 3; i++ {
+			inv.CallOld()
+			if inv.Err() == nil {
+				return
+			}
+		}
+	})
+
+	res, err := fetch(context.Background(), "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != "user:1" || fetchCount != 3 {
+		t.Fatalf("expect user:1 after 3 calls, actual: %s after %d calls", res, fetchCount)
+	}
+}
+
+func TestWrapRewriteArgsAndResults(t *testing.T) {
+	mock.Wrap(greet, func(inv *mock.Invocation) {
+		name := strings.TrimSpace(inv.Args[0].(string))
+		res := inv.Call(name)
+		inv.Results = []interface{}{strings.ToUpper(res[0].(string))}
+	})
+	// nested mocks still work inside the original function
+	mock.Patch(helper, func() string {
+		return "?"
+	})
+
+	res := greet("  xgo ")
+	if res != "HELLO XGO!?" {
+		t.Fatalf("expect HELLO XGO!?, actual: %q", res)
+	}
+}
+
+func TestWrapMethod(t *testing.T) {
+	c := &Client{prefix: "> "}
+	other := &Client{prefix: "# "}
+
+	mock.Wrap(c.Join, func(inv *mock.Invocation) {
+		inv.Args[0] = "-"
+		inv.CallOld()
+	})
+	if res := c.Join(",", "a", "b"); res != "> a-b" {
+		t.Fatalf("expect > a-b, actual: %q", res)
+	}
+	if res := other.Join(",", "a", "b"); res != "# a,b" {
+		t.Fatalf("expect other instance not affected, actual: %q", res)
+	}
+}
+
+func TestWrapNotCalled(t *testing.T) {
+	fetchCount = 0
+	mock.Wrap(fetch, func(inv *mock.Invocation) {})
+
+	res, err := fetch(context.Background(), "1")
+	if res != "" || err != nil || fetchCount != 0 {
+		t.Fatalf("expect zero results without calling original, actual: %q %v %d", res, err, fetchCount)
+	}
+}
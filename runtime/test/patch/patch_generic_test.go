@@ -0,0 +1,47 @@
+//go:build go1.18
+// +build go1.18
+
+package patch
+
+import (
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/mock/typed"
+)
+
+var patchVarGeneric = "hello"
+
+func TestPatchFuncGeneric(t *testing.T) {
+	typed.PatchFunc(greet, func(s string) string {
+		return "mock " + s
+	})
+
+	res := greet("world")
+	if res != "mock world" {
+		t.Fatalf("expect patched result to be %q, actual: %q", "mock world", res)
+	}
+}
+
+func TestPatchFuncGenericMethod(t *testing.T) {
+	ins := &struct_{
+		s: "world",
+	}
+	typed.PatchFunc(ins.greet, func() string {
+		return "mock " + ins.s
+	})
+
+	res := ins.greet()
+	if res != "mock world" {
+		t.Fatalf("expect patched result to be %q, actual: %q", "mock world", res)
+	}
+}
+
+func TestPatchVarGeneric(t *testing.T) {
+	typed.PatchVar(&patchVarGeneric, func() string {
+		return "mock"
+	})
+	b := patchVarGeneric
+	if b != "mock" {
+		t.Fatalf("expect patched variable to be %q, actual: %q", "mock", b)
+	}
+}
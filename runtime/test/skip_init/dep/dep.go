@@ -0,0 +1,11 @@
+package dep
+
+var Loaded []string
+
+func init() {
+	Loaded = append(Loaded, "first")
+}
+
+func init() {
+	Loaded = append(Loaded, "second")
+}
@@ -0,0 +1,40 @@
+package skip_init
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/xhd2015/xgo/runtime/test/skip_init/dep"
+	"github.com/xhd2015/xgo/runtime/trap"
+)
+
+//xgo:stub-init github.com/xhd2015/xgo/runtime/test/skip_init/dep
+
+const depPkg = "github.com/xhd2015/xgo/runtime/test/skip_init/dep"
+
+// go run ./cmd/xgo test --project-dir runtime -run TestStubInit -v ./test/skip_init
+func TestStubInit(t *testing.T) {
+	if len(dep.Loaded) != 0 {
+		t.Fatalf("expect init of dep skipped, actual loaded: %v", dep.Loaded)
+	}
+	var inits []*trap.SkippedInit
+	for _, s := range trap.SkippedInits() {
+		if s.Pkg == depPkg {
+			inits = append(inits, s)
+		}
+	}
+	if len(inits) != 2 {
+		t.Fatalf("expect 2 skipped inits of dep, actual: %d", len(inits))
+	}
+	for _, s := range inits {
+		s.Run()
+	}
+	// run at most once
+	inits[0].Run()
+
+	loaded := fmt.Sprint(dep.Loaded)
+	expectLoaded := "[first second]"
+	if loaded != expectLoaded {
+		t.Fatalf("expect loaded %s, actual: %s", expectLoaded, loaded)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"unsafe"
 
 	"github.com/xhd2015/xgo/runtime/core"
+	"github.com/xhd2015/xgo/runtime/internal/testhook"
 	"github.com/xhd2015/xgo/runtime/trap"
 )
 
@@ -25,7 +26,7 @@ type testInfo struct {
 }
 
 func init() {
-	__xgo_link_on_test_start(func(t *testing.T, fn func(t *testing.T)) {
+	testhook.OnTestStart(func(t *testing.T, fn func(t *testing.T)) {
 		name := t.Name()
 		if name == "" {
 			return
@@ -42,11 +43,6 @@ func init() {
 	})
 }
 
-// link by compiler
-func __xgo_link_on_test_start(fn func(t *testing.T, fn func(t *testing.T))) {
-	fmt.Fprintln(os.Stderr, "WARNING: failed to link __xgo_link_on_test_start(requires xgo).")
-}
-
 // link by compiler
 func __xgo_link_getcurg() unsafe.Pointer {
 	fmt.Fprintln(os.Stderr, "WARNING: failed to link __xgo_link_getcurg(requires xgo).")
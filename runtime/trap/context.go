@@ -0,0 +1,79 @@
+package trap
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+
+	"github.com/xhd2015/xgo/runtime/core"
+)
+
+// set once any context interceptor is added,
+// to avoid extracting ctx on every call
+var hasContextInterceptors int32
+
+type contextInterceptorsKey struct{}
+
+// contextInterceptor is a linked list carried
+// by context, latest first
+type contextInterceptor struct {
+	f           *core.FuncInfo
+	interceptor *Interceptor
+	next        *contextInterceptor
+}
+
+// WithContextInterceptor returns a context carrying `interceptor`
+// for `f`, whose first argument must be context.Context.
+// The interceptor applies to calls of `f` whose ctx is, or
+// derives from, the returned context, regardless of goroutine.
+// It is discarded together with the context, so no cancel
+// function is needed.
+func WithContextInterceptor(ctx context.Context, f *core.FuncInfo, interceptor *Interceptor) context.Context {
+	if ctx == nil {
+		panic("ctx cannot be nil")
+	}
+	if f == nil {
+		panic("func cannot be nil")
+	}
+	ensureTrapInstall()
+	Ignore(interceptor.Pre)
+	Ignore(interceptor.Post)
+	atomic.StoreInt32(&hasContextInterceptors, 1)
+
+	next, _ := ctx.Value(contextInterceptorsKey{}).(*contextInterceptor)
+	return context.WithValue(ctx, contextInterceptorsKey{}, &contextInterceptor{
+		f:           f,
+		interceptor: interceptor,
+		next:        next,
+	})
+}
+
+// getContextInterceptors returns interceptors of `f` carried by
+// the ctx argument, in the order they are added
+func getContextInterceptors(f *core.FuncInfo, args []interface{}) []*Interceptor {
+	if !f.FirstArgCtx || len(args) == 0 || atomic.LoadInt32(&hasContextInterceptors) == 0 {
+		return nil
+	}
+	ctx := getArgCtx(args[0])
+	if ctx == nil {
+		return nil
+	}
+	var list []*Interceptor
+	node, _ := ctx.Value(contextInterceptorsKey{}).(*contextInterceptor)
+	for ; node != nil; node = node.next {
+		if node.f == f {
+			list = append(list, node.interceptor)
+		}
+	}
+	// reverse to added order
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+	return list
+}
+
+func getArgCtx(arg interface{}) context.Context {
+	// NOTE: ctx can be nil when doing InspectPC
+	ctx, _ := reflect.ValueOf(arg).Elem().Interface().(context.Context)
+	return ctx
+}
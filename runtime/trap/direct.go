@@ -1,8 +1,18 @@
 package trap
 
-import "sync"
+import (
+	"sync"
+	"sync/atomic"
 
-var bypassMapping sync.Map // <goroutine key> -> struct{}{}
+	"github.com/xhd2015/xgo/runtime/core"
+)
+
+var bypassMapping sync.Map   // <goroutine key> -> struct{}{}
+var skipOnceMapping sync.Map // <goroutine key> -> *core.FuncInfo
+
+// number of running CallOriginal, trapped calls
+// look up skipOnceMapping only if it is not zero
+var skipOnceCount int32
 
 // Direct make a call to fn, without
 // any trap and mock interceptors
@@ -18,3 +28,38 @@ func isByPassing() bool {
 	_, ok := bypassMapping.Load(key)
 	return ok
 }
+
+// CallOriginal make a call to fn, the first trap of
+// `f` inside fn is skipped, so the original body of
+// `f` executes. Unlike Direct, interceptors of functions
+// called by `f` still work.
+// It is typically used by interceptors to call
+// the intercepted function again.
+func CallOriginal(f *core.FuncInfo, fn func()) {
+	key := uintptr(__xgo_link_getcurg())
+	prev, hasPrev := skipOnceMapping.Load(key)
+	skipOnceMapping.Store(key, f)
+	atomic.AddInt32(&skipOnceCount, 1)
+	defer func() {
+		atomic.AddInt32(&skipOnceCount, -1)
+		if hasPrev {
+			skipOnceMapping.Store(key, prev)
+		} else {
+			skipOnceMapping.Delete(key)
+		}
+	}()
+	fn()
+}
+
+func skipOnce(f *core.FuncInfo) bool {
+	if atomic.LoadInt32(&skipOnceCount) == 0 {
+		return false
+	}
+	key := uintptr(__xgo_link_getcurg())
+	v, ok := skipOnceMapping.Load(key)
+	if !ok || v.(*core.FuncInfo) != f {
+		return false
+	}
+	skipOnceMapping.Delete(key)
+	return true
+}
@@ -0,0 +1,110 @@
+package trap
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/xhd2015/xgo/runtime/core"
+)
+
+// Frame is a frame of the logical call chain
+type Frame struct {
+	// Name is the full name, i.e. github.com/a/b.(*T).M
+	Name string
+	Pkg  string
+	// File and Line are where the function is declared
+	File string
+	Line int
+	PC   uintptr
+
+	FuncInfo *core.FuncInfo
+}
+
+// number of TrackFrames not cancelled
+var trackFramesCount int32
+
+// TrackFrames records calls of all functions instrumented
+// by xgo into the call chain until the returned function
+// is called. Otherwise only calls with interceptors are
+// recorded. Caller-aware mock options call it implicitly.
+func TrackFrames() func() {
+	atomic.AddInt32(&trackFramesCount, 1)
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			atomic.AddInt32(&trackFramesCount, -1)
+		})
+	}
+}
+
+func isTrackingFrames() bool {
+	return atomic.LoadInt32(&trackFramesCount) > 0
+}
+
+// Frames returns the logical call chain kept by trap on
+// current goroutine, innermost first. It only contains
+// functions instrumented by xgo, see TrackFrames.
+// When called from an interceptor, the chain starts from
+// the caller of the intercepted function. Otherwise it
+// starts from the caller of the function calling Frames.
+func Frames() []*Frame {
+	val, ok := stackMapping.Load(uintptr(__xgo_link_getcurg()))
+	if !ok {
+		return nil
+	}
+	top := val.(*root).top
+	if top == nil {
+		return nil
+	}
+	var frames []*Frame
+	for s := top.parent; s != nil; s = s.parent {
+		frames = append(frames, newFrame(s))
+	}
+	return frames
+}
+
+// Caller returns the first frame of Frames(),
+// or nil if there is none
+func Caller() *Frame {
+	frames := Frames()
+	if len(frames) == 0 {
+		return nil
+	}
+	return frames[0]
+}
+
+func newFrame(s *stack) *Frame {
+	f := s.funcInfo
+	frame := &Frame{
+		Name:     f.FullName,
+		Pkg:      f.Pkg,
+		File:     f.File,
+		Line:     f.Line,
+		PC:       s.pc,
+		FuncInfo: f,
+	}
+	// use the same name as runtime, i.e.
+	// closures and generic instances
+	if fn := runtime.FuncForPC(s.pc); s.pc != 0 && fn != nil {
+		frame.Name = fn.Name()
+	} else if frame.Name == "" {
+		frame.Name = f.Pkg + "." + f.IdentityName
+	}
+	return frame
+}
+
+// pushFrame records a call without interceptors,
+// the returned function pops it
+func pushFrame(r *root, f *core.FuncInfo, pc uintptr) func() {
+	s := &stack{
+		parent:   r.top,
+		funcInfo: f,
+		stage:    stage_execute,
+		pc:       pc,
+	}
+	r.top = s
+	return func() {
+		r.top = s.parent
+	}
+}
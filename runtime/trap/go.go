@@ -0,0 +1,169 @@
+package trap
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// number of goroutines having go handler,
+// to avoid lookup on every go statement
+var goHandlerCount int32
+var goHandlers sync.Map // goroutine key -> func(fn func()) bool
+
+var goHookOnce sync.Once
+
+// path of the main module, go statements of its
+// packages are taken over
+var mainModule string
+
+// ErrGoHandlerUnsupported is returned by SetGoHandler
+// when the go version is below go1.18
+var ErrGoHandlerUnsupported = errors.New("taking over go statements requires go1.18 and above")
+
+// link by compiler
+func __xgo_link_set_go_hook(hook func(pc uintptr, fn func()) bool) {
+	fmt.Fprintln(os.Stderr, "WARNING: failed to link __xgo_link_set_go_hook(requires xgo).")
+}
+
+// SetGoHandler takes over go statements executed by current
+// goroutine: instead of starting a new goroutine, `handler`
+// is given the function the goroutine would run, with args
+// already evaluated. Returning false starts the goroutine as usual.
+// Only go statements of packages in the main module are
+// taken over, those of the standard library and dependencies,
+// i.e. connection loops of net/http, start goroutines as usual.
+// It requires go1.18 and above, otherwise ErrGoHandlerUnsupported
+// is returned.
+// The returned function restores the previous handler.
+func SetGoHandler(handler func(fn func()) bool) (func(), error) {
+	if handler == nil {
+		panic("handler cannot be nil")
+	}
+	if !goHookSupported {
+		return nil, ErrGoHandlerUnsupported
+	}
+	goHookOnce.Do(func() {
+		mainModule = resolveMainModule()
+		__xgo_link_set_go_hook(goHook)
+	})
+	key := uintptr(__xgo_link_getcurg())
+	prev, hasPrev := goHandlers.Load(key)
+	goHandlers.Store(key, handler)
+	if !hasPrev {
+		atomic.AddInt32(&goHandlerCount, 1)
+	}
+	removed := false
+	return func() {
+		if removed {
+			panic(fmt.Errorf("remove go handler more than once"))
+		}
+		removed = true
+		if hasPrev {
+			goHandlers.Store(key, prev)
+			return
+		}
+		clearGoHandler(key)
+	}, nil
+}
+
+func clearGoHandler(key uintptr) {
+	if _, ok := goHandlers.Load(key); ok {
+		goHandlers.Delete(key)
+		atomic.AddInt32(&goHandlerCount, -1)
+	}
+}
+
+// goHook is called by newproc, pc is
+// the return address of the go statement
+func goHook(pc uintptr, fn func()) bool {
+	if atomic.LoadInt32(&goHandlerCount) == 0 {
+		return false
+	}
+	val, ok := goHandlers.Load(uintptr(__xgo_link_getcurg()))
+	if !ok || isByPassing() {
+		return false
+	}
+	caller := runtime.FuncForPC(pc - 1)
+	if caller == nil {
+		return false
+	}
+	name := caller.Name()
+	if isInternalFrame(name) || !isMainModulePkg(funcPkg(name)) {
+		return false
+	}
+	return val.(func(fn func()) bool)(fn)
+}
+
+// resolveMainModule returns the main module set by
+// xgo, or recorded in build info
+func resolveMainModule() string {
+	if mod := os.Getenv("XGO_MAIN_MODULE"); mod != "" {
+		return mod
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		return info.Main.Path
+	}
+	return ""
+}
+
+func isMainModulePkg(pkg string) bool {
+	if pkg == "main" {
+		return true
+	}
+	if mainModule == "" {
+		return false
+	}
+	return pkg == mainModule || strings.HasPrefix(pkg, mainModule+"/")
+}
+
+const xgoRuntimePrefix = "github.com/xhd2015/xgo/runtime/"
+const xgoRuntimeTestPrefix = "github.com/xhd2015/xgo/runtime/test/"
+
+func isInternalFrame(name string) bool {
+	if strings.HasPrefix(name, xgoRuntimePrefix) {
+		return !strings.HasPrefix(name, xgoRuntimeTestPrefix)
+	}
+	return strings.HasPrefix(name, "runtime.") || strings.HasPrefix(name, "reflect.")
+}
+
+// funcPkg extracts package path from full name:
+//
+//	a/b/c.A -> a/b/c
+//	a/b/c.(*C).X -> a/b/c
+//	gopkg.in/yaml%2ev3.Unmarshal -> gopkg.in/yaml.v3
+func funcPkg(name string) string {
+	base := strings.LastIndex(name, "/") + 1
+	dot := strings.Index(name[base:], ".")
+	if dot >= 0 {
+		name = name[:base+dot]
+	}
+	return unescapeSymbolPath(name)
+}
+
+// unescapeSymbolPath reverts the escaping of the last path
+// element in symbol names, where '.' and other special
+// characters are written as %xx
+func unescapeSymbolPath(path string) string {
+	if !strings.Contains(path, "%") {
+		return path
+	}
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] == '%' && i+2 < len(path) {
+			if c, err := strconv.ParseUint(path[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
+}
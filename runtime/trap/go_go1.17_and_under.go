@@ -0,0 +1,7 @@
+//go:build !go1.18
+// +build !go1.18
+
+package trap
+
+// newproc is patched to call the go hook since go1.18
+const goHookSupported = false
@@ -218,13 +218,16 @@ func getLocalInterceptorGroup() *interceptorGroup {
 
 // f must not be nil
 // if `noCommon` is set, only get f's mapping interceptors
+// `contextFunc` are f's interceptors carried by ctx, see WithContextInterceptor
 // TODO: may allow trace when set `noLocalCommon`
-func getAllInterceptors(f *core.FuncInfo, needCommon bool) ([]*Interceptor, int) {
+func getAllInterceptors(f *core.FuncInfo, needCommon bool, contextFunc []*Interceptor) ([]*Interceptor, int) {
 	group := getLocalInterceptorGroup()
 
 	var globalHead []*Interceptor
 	var globalTail []*Interceptor
 
+	testFunc := getTestScopeInterceptors(f)
+
 	var localHead []*Interceptor
 	var localTail []*Interceptor
 	var localFunc []*Interceptor
@@ -249,8 +252,10 @@ func getAllInterceptors(f *core.FuncInfo, needCommon bool) ([]*Interceptor, int)
 		globalTail = globalInterceptors.tail
 	}
 
-	// run locals first(in reversed order)
-	return mergeInterceptors(globalTail, localFunc, localTail, globalHead, localHead), g
+	// run locals first(in reversed order),
+	// context interceptors are more specific than local ones,
+	// and test scoped ones are less
+	return mergeInterceptors(globalTail, testFunc, localFunc, contextFunc, localTail, globalHead, localHead), g
 }
 
 // returns a function to dispose the key
@@ -0,0 +1,58 @@
+package trap
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// link by compiler
+func __xgo_link_get_skipped_inits(f func(pkgPath string, file string, line int, fn func())) {
+	fmt.Fprintln(os.Stderr, "WARNING: failed to link __xgo_link_get_skipped_inits(requires xgo).")
+}
+
+// SkippedInit is an init function not run at program start,
+// because its package matched `xgo test --skip-init` or
+// an `//xgo:stub-init` directive
+type SkippedInit struct {
+	Pkg  string
+	File string
+	Line int
+
+	fn   func()
+	once sync.Once
+}
+
+// Run runs the original init function, at most once
+func (c *SkippedInit) Run() {
+	c.once.Do(c.fn)
+}
+
+var skippedInitsOnce sync.Once
+var skippedInits []*SkippedInit
+
+// SkippedInits returns skipped init functions in the
+// order they would have run. A test can leave them
+// skipped to replace them, or Run them after setting
+// up mocks to wrap them:
+//
+//	func TestMain(m *testing.M) {
+//		mock.Patch(config.Load, func() (*config.Config, error) { return &config.Config{}, nil })
+//		for _, s := range trap.SkippedInits() {
+//			s.Run()
+//		}
+//		os.Exit(m.Run())
+//	}
+func SkippedInits() []*SkippedInit {
+	skippedInitsOnce.Do(func() {
+		__xgo_link_get_skipped_inits(func(pkgPath string, file string, line int, fn func()) {
+			skippedInits = append(skippedInits, &SkippedInit{
+				Pkg:  pkgPath,
+				File: file,
+				Line: line,
+				fn:   fn,
+			})
+		})
+	})
+	return skippedInits
+}
@@ -0,0 +1,132 @@
+package trap
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/xhd2015/xgo/runtime/core"
+)
+
+// testScope holds interceptors bound to a test, shared
+// by all goroutines started under the test, including
+// those started before the interceptors are added
+type testScope struct {
+	parent *testScope
+
+	mutex  sync.RWMutex
+	list   *interceptorManager
+	closed bool
+}
+
+var testScopes sync.Map       // goroutine key -> *testScope
+var testScopeMapping sync.Map // test -> *testScope
+
+// set when the first test scoped interceptor is added,
+// to avoid lookup on every trapped call
+var hasTestScopeInterceptors int32
+
+// StartTestScope binds a new scope of test `t` to current
+// goroutine, the scope is closed when `t` finishes.
+// It is called by runtime/mock when each test starts,
+// so that AddTestFuncInfoInterceptor can find the scope.
+func StartTestScope(t interface{ Cleanup(func()) }) {
+	if t == nil {
+		panic("t cannot be nil")
+	}
+	key := uintptr(__xgo_link_getcurg())
+	// subtests inherit parent's scope when started
+	parent := getTestScope(key)
+	scope := &testScope{
+		parent: parent,
+		list:   &interceptorManager{},
+	}
+	testScopes.Store(key, scope)
+	testScopeMapping.Store(t, scope)
+	t.Cleanup(func() {
+		scope.close()
+		testScopeMapping.Delete(t)
+	})
+}
+
+// AddTestFuncInfoInterceptor adds interceptor of f bound to test `t`,
+// it applies to every goroutine started under `t` and its subtests,
+// no matter the goroutine is started before or after.
+// It is removed when `t` finishes.
+func AddTestFuncInfoInterceptor(t interface{ Cleanup(func()) }, f *core.FuncInfo, interceptor *Interceptor) func() {
+	if t == nil {
+		panic("t cannot be nil")
+	}
+	if f == nil {
+		panic(fmt.Errorf("func cannot be nil"))
+	}
+	val, ok := testScopeMapping.Load(t)
+	if !ok {
+		panic(fmt.Errorf("test scope not found, requires xgo test"))
+	}
+	ensureTrapInstall()
+	Ignore(interceptor.Pre)
+	Ignore(interceptor.Post)
+
+	scope := val.(*testScope)
+	scope.mutex.Lock()
+	defer scope.mutex.Unlock()
+	if scope.closed {
+		panic(fmt.Errorf("test already finished"))
+	}
+	scope.list.append(f, interceptor, false)
+	atomic.StoreInt32(&hasTestScopeInterceptors, 1)
+
+	removed := false
+	return func() {
+		scope.mutex.Lock()
+		defer scope.mutex.Unlock()
+		if removed {
+			panic(fmt.Errorf("remove interceptor more than once"))
+		}
+		removed = true
+		if scope.closed {
+			return
+		}
+		scope.list.removeInterceptor(f, interceptor, false)
+	}
+}
+
+func (c *testScope) close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.closed = true
+	c.list = &interceptorManager{}
+}
+
+func getTestScope(key uintptr) *testScope {
+	val, ok := testScopes.Load(key)
+	if !ok {
+		return nil
+	}
+	return val.(*testScope)
+}
+
+// getTestScopeInterceptors returns f's interceptors of
+// current test and its parents, parents first
+func getTestScopeInterceptors(f *core.FuncInfo) []*Interceptor {
+	if atomic.LoadInt32(&hasTestScopeInterceptors) == 0 {
+		return nil
+	}
+	scope := getTestScope(uintptr(__xgo_link_getcurg()))
+	if scope == nil {
+		return nil
+	}
+	var scopes []*testScope
+	for ; scope != nil; scope = scope.parent {
+		scopes = append(scopes, scope)
+	}
+	var list []*Interceptor
+	for i := len(scopes) - 1; i >= 0; i-- {
+		scope := scopes[i]
+		scope.mutex.RLock()
+		list = append(list, scope.list.funcMapping[f]...)
+		scope.mutex.RUnlock()
+	}
+	return list
+}
@@ -37,6 +37,10 @@ func init() {
 		if isByPassing() {
 			return
 		}
+		// share the test scope, see AddTestFuncInfoInterceptor
+		if scope := getTestScope(uintptr(__xgo_link_getcurg())); scope != nil {
+			testScopes.Store(g, scope)
+		}
 		local := getLocalInterceptorList()
 		if local == nil || (len(local.head) == 0 && len(local.tail) == 0) {
 			return
@@ -86,6 +90,7 @@ type stack struct {
 	funcInfo *core.FuncInfo
 	stage    stage
 	pc       uintptr // the actual pc
+	typeArgs []interface{}
 }
 
 type stage int
@@ -102,6 +107,14 @@ func trapFunc(pkgPath string, identityName string, generic bool, pc uintptr, rec
 	if isByPassing() {
 		return nil, false
 	}
+	var typeArgs []interface{}
+	if generic && len(args) > 0 {
+		// compiler appends type args as (*T)(nil) after args
+		if v, ok := args[len(args)-1].([]interface{}); ok {
+			typeArgs = v
+			args = args[:len(args)-1]
+		}
+	}
 	inspectingFn, inspecting := inspectingMap.Load(uintptr(__xgo_link_getcurg()))
 
 	// NOTE: this may return nil for generic template
@@ -122,6 +135,10 @@ func trapFunc(pkgPath string, identityName string, generic bool, pc uintptr, rec
 		// let go to the next interceptor
 		return nil, false
 	}
+	if skipOnce(f) {
+		// see CallOriginal
+		return nil, false
+	}
 	if inspecting {
 		inspectingFn.(inspectingFunc)(f, recv, pc)
 		// abort,never really call the target
@@ -132,7 +149,7 @@ func trapFunc(pkgPath string, identityName string, generic bool, pc uintptr, rec
 		return nil, false
 	}
 
-	return trap(f, pc, recv, args, results)
+	return trap(f, pc, recv, args, results, typeArgs)
 }
 
 func trapVar(pkgPath string, name string, tmpVarAddr interface{}, takeAddr bool) {
@@ -151,14 +168,14 @@ func trapVar(pkgPath string, name string, tmpVarAddr interface{}, takeAddr bool)
 		return
 	}
 	// NOTE: stop always ignored because this is a simple get
-	post, _ := trap(fnInfo, 0, nil, nil, []interface{}{tmpVarAddr})
+	post, _ := trap(fnInfo, 0, nil, nil, []interface{}{tmpVarAddr}, nil)
 	if post != nil {
 		// NOTE: must in defer, because in post we
 		// may capture panic
 		defer post()
 	}
 }
-func trap(f *core.FuncInfo, pc uintptr, recv interface{}, args []interface{}, results []interface{}) (func(), bool) {
+func trap(f *core.FuncInfo, pc uintptr, recv interface{}, args []interface{}, results []interface{}, typeArgs []interface{}) (func(), bool) {
 	// never trap any function from runtime
 	key := uintptr(__xgo_link_getcurg())
 	r := &root{}
@@ -167,9 +184,12 @@ func trap(f *core.FuncInfo, pc uintptr, recv interface{}, args []interface{}, re
 		r = rv.(*root)
 	}
 	// fmt.Printf("trap: %s.%s intercepting=%v\n", f.Pkg, f.IdentityName, r.intercepting)
-	interceptors, _ := getAllInterceptors(f, !r.intercepting)
+	interceptors, _ := getAllInterceptors(f, !r.intercepting, getContextInterceptors(f, args))
 	n := len(interceptors)
 	if n == 0 {
+		if f.Kind == core.Kind_Func && isTrackingFrames() {
+			return pushFrame(r, f, pc), false
+		}
 		return nil, false
 	}
 
@@ -187,6 +207,7 @@ func trap(f *core.FuncInfo, pc uintptr, recv interface{}, args []interface{}, re
 		funcInfo: f,
 		stage:    stage_pre,
 		pc:       pc,
+		typeArgs: typeArgs,
 	}
 	r.top = stack
 
@@ -371,10 +392,33 @@ func GetTrappingPC() uintptr {
 	return top.pc
 }
 
+// GetTypeArgs returns type arguments of the generic
+// function or method being intercepted, i.e. [int string]
+// for Map[int,string], it should be called inside an
+// interceptor. It returns nil for non-generic functions.
+func GetTypeArgs() []reflect.Type {
+	val, ok := stackMapping.Load(uintptr(__xgo_link_getcurg()))
+	if !ok {
+		return nil
+	}
+	top := val.(*root).top
+	if top == nil || len(top.typeArgs) == 0 {
+		return nil
+	}
+	types := make([]reflect.Type, len(top.typeArgs))
+	for i, typeArg := range top.typeArgs {
+		types[i] = reflect.TypeOf(typeArg).Elem()
+	}
+	return types
+}
+
 func clearLocalInterceptorsAndMark() {
 	key := uintptr(__xgo_link_getcurg())
 	localInterceptors.Delete(key)
 	bypassMapping.Delete(key)
+	skipOnceMapping.Delete(key)
+	testScopes.Delete(key)
+	clearGoHandler(key)
 
 	stackMapping.Delete(key)
 }
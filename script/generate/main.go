@@ -25,6 +25,7 @@ const (
 	GenernateType_RuntimeDef         GenernateType = "runtime-def"
 	GenernateType_StackTraceDef      GenernateType = "stack-trace-def"
 	GenernateType_InstallSrc         GenernateType = "install-src"
+	GenernateType_PatternMatch       GenernateType = "pattern-match"
 )
 
 func main() {
@@ -103,6 +104,15 @@ func generate(rootDir string, subGens SubGens) error {
 			return err
 		}
 	}
+	if subGens.Has(GenernateType_PatternMatch) {
+		err := copyPatternMatch(
+			filepath.Join(rootDir, "runtime", "core", "pattern.go"),
+			filepath.Join(rootDir, "patch", "ctxt", "match_gen.go"),
+		)
+		if err != nil {
+			return err
+		}
+	}
 	if subGens.Has(GenernateType_CompilerHelperCode) {
 		info, err := generateFuncHelperCode(filepath.Join(rootDir, "patch", "syntax", "helper_code.go"))
 		if err != nil {
@@ -254,6 +264,18 @@ func copyTraceExport(srcFile string, targetFile string) error {
 	return os.WriteFile(targetFile, []byte(content), 0755)
 }
 
+func copyPatternMatch(srcFile string, targetFile string) error {
+	contentBytes, err := os.ReadFile(srcFile)
+	if err != nil {
+		return err
+	}
+	content := string(contentBytes)
+	content = strings.Replace(content, "package core", "package ctxt", 1)
+	content = prelude + content
+
+	return os.WriteFile(targetFile, []byte(content), 0755)
+}
+
 func copyUpgrade(srcDir string, targetDir string) error {
 	err := filecopy.CopyReplaceDir(srcDir, targetDir, false)
 	if err != nil {
@@ -42,6 +42,26 @@ var runtimeSubTests = []string{
 	"trace_marshal",
 	"trace_panic_peek",
 	"mock_func",
+	"mock_controller",
+	"mock_stub",
+	"mock_spy",
+	"mock_cassette",
+	"mock_interface",
+	"mock_pattern",
+	"mock_wrap",
+	"mock_caller",
+	"mock_context",
+	"mock_test_scope",
+	"mock_goroutine",
+	"mock_isolate",
+	"skip_init",
+	"mock_clock",
+	"mock_fsfake",
+	"mock_httpfake",
+	"mock_execfake",
+	"mock_env",
+	"chaos",
+	"leak",
 	"mock_method",
 	"mock_by_name",
 	"mock_closure",
@@ -0,0 +1,64 @@
+package test
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/xhd2015/xgo/support/osinfo"
+)
+
+// go test -run TestDeterministicMaps -v ./test
+func TestDeterministicMaps(t *testing.T) {
+	t.Parallel()
+	bin, err := getTempFile("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin += osinfo.EXE_SUFFIX
+	defer os.RemoveAll(bin)
+
+	_, err = runXgo([]string{"-o", bin, "./testdata/deterministic_maps"}, &options{
+		xgoCmd: xgoCmd_build,
+	})
+	if err != nil {
+		t.Fatal(getErrMsg(err))
+	}
+	run := func(seed string, args ...string) string {
+		cmd := exec.Command(bin, args...)
+		cmd.Env = append(os.Environ(), "XGO_DETERMINISTIC_MAPS="+seed)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatal(getErrMsg(err))
+		}
+		return string(out)
+	}
+
+	output := run("7")
+	lines := strings.Split(output, "\n")
+	if len(lines) < 2 || strings.TrimPrefix(lines[0], "first: ") != strings.TrimPrefix(lines[1], "second: ") {
+		t.Fatalf("expect same order when ranging a map twice, actual: %s", output)
+	}
+	// maps filled the same way iterate in the same order,
+	// no matter which goroutine creates them
+	first := strings.TrimPrefix(lines[0], "first: ")
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "goroutine ") {
+			continue
+		}
+		if line[strings.Index(line, ": ")+2:] != first {
+			t.Fatalf("expect maps created concurrently to have the same order, actual: %s", output)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		again := run("7")
+		if again != output {
+			t.Fatalf("expect same output with the same seed, expect: %s, actual: %s", output, again)
+		}
+	}
+	extra := run("7", "extra")
+	if extra != output {
+		t.Fatalf("expect maps created before not to change the order, expect: %s, actual: %s", output, extra)
+	}
+}
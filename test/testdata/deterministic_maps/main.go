@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// go run ./cmd/xgo build -o /tmp/maps ./test/testdata/deterministic_maps && XGO_DETERMINISTIC_MAPS=7 /tmp/maps
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "extra" {
+		// maps created before should not
+		// change the order of later maps
+		for i := 0; i < 100; i++ {
+			_ = map[int]int{i: i}
+		}
+	}
+	m := make(map[string]int)
+	for i := 0; i < 64; i++ {
+		m["k"+strconv.Itoa(i)] = i
+	}
+	small := map[int]bool{1: true, 2: true, 3: true, 4: true, 5: true}
+
+	fmt.Printf("first: %s\n", keys(m))
+	fmt.Printf("second: %s\n", keys(m))
+	fmt.Printf("small: %v\n", intKeys(small))
+
+	// maps created concurrently
+	const n = 8
+	results := make([]string, n)
+	var wg sync.WaitGroup
+	for g := 0; g < n; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < g*10; i++ {
+				_ = map[int]int{i: i}
+			}
+			m := make(map[string]int)
+			for i := 0; i < 64; i++ {
+				m["k"+strconv.Itoa(i)] = i
+			}
+			results[g] = keys(m)
+		}(g)
+	}
+	wg.Wait()
+	for g, result := range results {
+		fmt.Printf("goroutine %d: %s\n", g, result)
+	}
+}
+
+func keys(m map[string]int) string {
+	var list []string
+	for k := range m {
+		list = append(list, k)
+	}
+	return strings.Join(list, ",")
+}
+
+func intKeys(m map[int]bool) []int {
+	var list []int
+	for k := range m {
+		list = append(list, k)
+	}
+	return list
+}